@@ -0,0 +1,14 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package buildtags tests passing build flags to gopy bind.
+package buildtags
+
+// version is set with -ldflags="-X ...".
+var version = "devel"
+
+// Version returns the version the package was built with.
+func Version() string {
+	return version
+}
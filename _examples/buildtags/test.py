@@ -0,0 +1,11 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import buildtags
+
+print("buildtags.Version() = %s" % buildtags.Version())
+print("buildtags.Extra() = %s" % buildtags.Extra())
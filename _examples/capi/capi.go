@@ -0,0 +1,102 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package capi tests the plain C API of a Go package.
+package capi
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Max is the maximum value of a Counter.
+const Max = 10
+
+// Greeting is used by Hello.
+var Greeting = "hello"
+
+// Add returns the sum of a and b.
+func Add(a, b int) int {
+	return a + b
+}
+
+// Hello greets name.
+func Hello(name string) string {
+	return fmt.Sprintf("%s %s", Greeting, name)
+}
+
+// Div returns a/b.
+func Div(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+// Counter counts up to Max.
+type Counter struct {
+	Name string // name of the counter
+	N    int    // current value
+}
+
+// NewCounter returns a new counter named name.
+func NewCounter(name string) *Counter {
+	return &Counter{Name: name}
+}
+
+// Incr increments the counter by n.
+func (c *Counter) Incr(n int) error {
+	if c.N+n > Max {
+		return fmt.Errorf("%s: overflow", c.Name)
+	}
+	c.N += n
+	return nil
+}
+
+// Sum returns the sum of the values of the counters a and b.
+func Sum(a, b *Counter) int {
+	return a.N + b.N
+}
+
+// Split returns n counters, sharing the value of c.
+func Split(c *Counter, n int) []Counter {
+	counters := make([]Counter, n)
+	for i := range counters {
+		counters[i] = Counter{Name: fmt.Sprintf("%s%d", c.Name, i), N: c.N / n}
+	}
+	return counters
+}
+
+// Names can not be passed through the C API.
+func Names(c *Counter) map[string]int {
+	return map[string]int{c.Name: c.N}
+}
+
+// Stats records values.
+type Stats struct {
+	Name   string
+	Values []int   // recorded values
+	Range  [2]int  // smallest and largest recorded values
+	Last   Counter // counter of the last recorded value
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%s: %v in %v", s.Name, s.Values, s.Range)
+}
+
+// Total holds the values recorded by Record.
+var Total = Stats{Name: "total"}
+
+// Record records v in Total, and returns the number of recorded values.
+func Record(v int) int {
+	if len(Total.Values) == 0 || v < Total.Range[0] {
+		Total.Range[0] = v
+	}
+	if len(Total.Values) == 0 || v > Total.Range[1] {
+		Total.Range[1] = v
+	}
+	Total.Values = append(Total.Values, v)
+	Total.Last.N = v
+	return len(Total.Values)
+}
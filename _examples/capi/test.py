@@ -0,0 +1,76 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import capi
+
+print("capi.Max = %s" % (capi.Max,))
+print("capi.Add(1, 2) = %s" % (capi.Add(1, 2),))
+print("capi.Hello('gopy') = %s" % (capi.Hello("gopy"),))
+
+print("capi.Greeting = 'bonjour'")
+capi.Greeting = "bonjour"
+print("capi.GetGreeting() = %s" % (capi.GetGreeting(),))
+print("capi.Hello('gopy') = %s" % (capi.Hello("gopy"),))
+
+print("capi.Div(1, 2) = %s" % (capi.Div(1, 2),))
+try:
+    capi.Div(1, 0)
+except RuntimeError as err:
+    print("caught error: %s" % (err,))
+
+c = capi.NewCounter("c")
+print("c = %s" % (c,))
+c.Incr(4)
+try:
+    c.Incr(7)
+except RuntimeError as err:
+    print("caught error: %s" % (err,))
+print("c.Name = %s, c.N = %s" % (c.Name, c.N))
+
+d = capi.Counter(Name="d", N=2)
+print("d = %s" % (d,))
+print("d.to_dict() = %s" % (sorted(d.to_dict().items()),))
+print("capi.Sum(c, d) = %s" % (capi.Sum(c, d),))
+
+try:
+    capi.Counter("d", 2, 3)
+except TypeError as err:
+    print("caught error: %s" % (err,))
+
+cs = capi.Split(c, 2)
+print("len(cs) = %s" % (len(cs),))
+cs[1].N = 3  # the elements of slices are copies
+print("cs[0] = %s" % (cs[0],))
+print("cs[1] = %s" % (cs[1],))
+
+s = capi.Stats.from_dict({
+    "Name": "s",
+    "Values": [1, 2],
+    "Range": [1, 2],
+    "Last": {"Name": "l", "N": 2},
+})
+print("s = %s" % (s,))
+s.Values += [5]
+s.Range[1] = 5
+s.Last.N = 5
+print("s = %s" % (s,))
+print("s.Last = %s" % (s.Last,))
+print("len(s.Values) = %s, s.Values[2] = %s" % (len(s.Values), s.Values[2]))
+d = s.to_dict()
+print("sorted(d) = %s" % (sorted(d),))
+print("d['Values'] = %s, d['Range'] = %s" % (d["Values"], d["Range"]))
+print("d['Last'] = %s" % (sorted(d["Last"].items()),))
+try:
+    capi.Stats.from_dict({"Count": 1})
+except TypeError as err:
+    print("caught error: %s" % (err,))
+
+capi.Total.Name = "all"
+print("capi.Record(3) = %s" % (capi.Record(3),))
+print("capi.Record(1) = %s" % (capi.Record(1),))
+print("capi.Total = %s" % (capi.Total,))
+print("capi.Total.Last.N = %s" % (capi.Total.Last.N,))
@@ -0,0 +1,39 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// package cmplx tests various aspects of complex numbers.
+package cmplx
+
+// Phasor is a named complex number
+type Phasor complex128
+
+// Real returns the real part of a phasor
+func (p Phasor) Real() float64 { return real(p) }
+
+// Add returns the sum of two complex128 numbers.
+func Add(a, b complex128) complex128 {
+	return a + b
+}
+
+// Conj returns the complex conjugate of c.
+func Conj(c complex64) complex64 {
+	return complex(real(c), -imag(c))
+}
+
+// Scale returns p scaled by f.
+func Scale(p Phasor, f float64) Phasor {
+	return p * Phasor(complex(f, 0))
+}
+
+// Slice is a slice of complex numbers
+type Slice []complex128
+
+// NewSlice returns a slice of n complex numbers.
+func NewSlice(n int) Slice {
+	s := make(Slice, n)
+	for i := range s {
+		s[i] = complex(float64(i), float64(-i))
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import cmplx
+
+print("cmplx.Add(1+2j, 3-1j) = %s" % (cmplx.Add(1+2j, 3-1j),))
+print("cmplx.Add(1, 2.5) = %s" % (cmplx.Add(1, 2.5),))
+print("cmplx.Conj(1+2j) = %s" % (cmplx.Conj(1+2j),))
+
+try:
+    cmplx.Add("1", 2)
+except TypeError as err:
+    print("caught: %s" % (err,))
+
+p = cmplx.Phasor(1+2j)
+print("p = %s" % (p,))
+print("p.Real() = %s" % (p.Real(),))
+print("complex(p) = %s" % (complex(p),))
+print("p + 1j = %s" % (p + 1j,))
+print("2 * p = %s" % (2 * p,))
+print("p - p = %s" % (p - p,))
+print("p / 2 = %s" % (p / 2,))
+print("-p = %s" % (-p,))
+print("abs(cmplx.Phasor(3+4j)) = %s" % (abs(cmplx.Phasor(3+4j)),))
+print("bool(p - p) = %s" % (bool(p - p),))
+print("type(p * p) = %s" % (type(p * p).__name__,))
+print("cmplx.Scale(p, 2) = %s" % (cmplx.Scale(p, 2),))
+print("cmplx.Scale(3j, 2) = %s" % (cmplx.Scale(3j, 2),))
+
+try:
+    p / 0
+except ZeroDivisionError as err:
+    print("caught: %s" % (err,))
+
+s = cmplx.NewSlice(3)
+print("s = %s" % (s,))
+print("s[1] = %s" % (s[1],))
+s[1] = 5j
+print("s[1] = %s" % (s[1],))
+m = memoryview(s)
+print("mem(s): format=%s itemsize=%d len=%d" % (m.format, m.itemsize, len(m)))
@@ -0,0 +1,78 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package config tests the binding configuration of a package, read from its
+// gopy.json file.
+package config
+
+// Version is the version of the package.
+const Version = "1.0"
+
+// Debug is excluded by the configuration.
+var Debug = false
+
+// Color is a color, exposed as an enum class although it has a single
+// constant.
+type Color int
+
+const Red Color = 1
+
+// Mode is a mode, whose constants are not exposed as an enum class.
+type Mode int
+
+const (
+	Read Mode = iota
+	Write
+)
+
+// Internal is excluded by the configuration.
+type Internal struct {
+	N int
+}
+
+// Describe is not bound, as it uses the excluded type Internal.
+func Describe(i Internal) string {
+	return "internal"
+}
+
+// Point is a point with a label.
+type Point struct {
+	X, Y   int
+	Label  string // exposed as name
+	Secret string // excluded by the configuration
+}
+
+// NewPoint is the only constructor of Point.
+func NewPoint(x, y int) Point {
+	return Point{X: x, Y: y, Label: "p"}
+}
+
+// Origin returns the origin.
+func Origin() Point {
+	return Point{Label: "origin"}
+}
+
+// Norm2 returns the squared norm of p.
+func (p *Point) Norm2() int {
+	return p.X*p.X + p.Y*p.Y
+}
+
+// Reset is excluded by the configuration.
+func (p *Point) Reset() {
+	*p = Point{}
+}
+
+// Sum returns the sum of the integers up to n, with the GIL released.
+func Sum(n int) int {
+	s := 0
+	for i := 1; i <= n; i++ {
+		s += i
+	}
+	return s
+}
+
+// Double returns twice x.
+func Double(x int) int {
+	return 2 * x
+}
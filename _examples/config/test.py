@@ -0,0 +1,33 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import config
+import simple
+
+print("config.VERSION_STRING = %r" % (config.VERSION_STRING,))
+print("config.RED = %r" % (config.RED,))
+print("config.Color(1) = %r" % (config.Color(1),))
+print("isinstance(config.RED, int) = %s" % (isinstance(config.RED, int),))
+print("isinstance(config.READ, int) = %s" % (isinstance(config.READ, int),))
+print("type(config.WRITE) = %s" % (type(config.WRITE).__name__,))
+
+p = config.new_point(3, 4)
+print("p.x, p.y = %s, %s" % (p.x, p.y))
+print("p.name = %r" % (p.name,))
+print("p.norm2() = %s" % (p.norm2(),))
+print("p.to_dict() = %s" % (sorted(p.to_dict().items()),))
+print("config.origin().name = %r" % (config.origin().name,))
+
+print("config.sum_(100) = %s" % (config.sum_(100),))
+print("config.twice(21) = %s" % (config.twice(21),))
+
+for name in ["debug", "get_debug", "Internal", "describe", "double"]:
+    print("hasattr(config, %r) = %s" % (name, hasattr(config, name)))
+for name in ["secret", "reset", "label"]:
+    print("hasattr(p, %r) = %s" % (name, hasattr(p, name)))
+
+print("simple.Answer(1, 'a') = %s" % (simple.Answer(1, "a"),))
@@ -17,6 +17,9 @@ const (
 	C7 float64 = 666.666
 )
 
+// Big does not fit into an int64.
+const Big = 1 << 70
+
 type Kind int
 
 const (
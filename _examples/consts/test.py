@@ -17,6 +17,17 @@ print("c7 = %s" % consts.GetC7())
 
 print("k1 = %s" % consts.GetKind1())
 print("k2 = %s" % consts.GetKind2())
+print("consts.C1 = %r" % (consts.C1,))
+print("consts.C2 = %r" % (consts.C2,))
+print("consts.C3 = %r" % (consts.C3,))
+print("consts.C4 = %r" % (consts.C4,))
+print("consts.C5 = %r" % (consts.C5,))
+print("consts.C6 = %r" % (consts.C6,))
+print("consts.C7 = %r" % (consts.C7,))
+print("consts.Big = %r" % (consts.Big,))
+print("consts.Kind1 = %s (%s)" % (consts.Kind1, type(consts.Kind1).__name__))
+print("consts.Kind2 = %r" % (consts.Kind2,))
+
 ## FIXME: unexported types not supported yet (issue #44)
 #print("k3 = %s" % consts.GetKind3())
 #print("k4 = %s" % consts.GetKind4())
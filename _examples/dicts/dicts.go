@@ -0,0 +1,35 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// package dicts tests the conversion of structs from and to python dicts.
+package dicts
+
+import (
+	"fmt"
+)
+
+type Celsius float64
+
+type Address struct {
+	Street string `json:"street"`
+	Zip    int    `json:"zip,omitempty"`
+}
+
+type Person struct {
+	Name    string         `json:"name"`
+	Age     int            `json:"age"`
+	Temp    Celsius        // no tag: the Go name is used
+	Home    Address        `json:"home"`
+	Tags    []string       `json:"tags"`
+	Scores  map[string]int `json:"scores"`
+	Secret  string         `json:"-"`
+	private int
+}
+
+// Describe returns a summary of the content of p.
+func Describe(p Person) string {
+	return fmt.Sprintf("%s (%d) at %s/%d, temp=%v tags=%v scores=%d secret=%q",
+		p.Name, p.Age, p.Home.Street, p.Home.Zip, float64(p.Temp), p.Tags, len(p.Scores), p.Secret,
+	)
+}
@@ -0,0 +1,51 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import dicts
+
+def show(d):
+    return "{%s}" % ", ".join("%r: %r" % (k, d[k]) for k in sorted(d))
+
+p = dicts.Person.from_dict({
+    "name": "Bob",
+    "age": 42,
+    "Temp": 36.6,
+    "home": {"street": "Main", "zip": 1234},
+    "tags": ["a", "b"],
+    "scores": {"math": 12},
+})
+print("p = %s" % (dicts.Describe(p),))
+
+d = p.to_dict()
+print("p.to_dict() = %s" % (show(d),))
+print("home = %s" % (show(d["home"]),))
+print("types: %s %s %s" % (type(d["Temp"]).__name__, type(d["tags"]).__name__, type(d["scores"]).__name__))
+
+q = dicts.Person.from_dict(d)
+print("roundtrip = %s" % (dicts.Describe(q),))
+
+p = dicts.Person.from_dict({"home": dicts.Address("Elm", 7)})
+print("p = %s" % (dicts.Describe(p),))
+
+for bad in [
+    {"age": "42"},
+    {"nickname": "bobby"},
+    {"Secret": "xxx"},
+    {"home": {"zip": "1234"}},
+    {"home": 42},
+    {"tags": [1, 2]},
+    {"scores": {"math": "A"}},
+    ]:
+    try:
+        dicts.Person.from_dict(bad)
+    except TypeError as err:
+        print("caught: %s" % (err,))
+
+try:
+    dicts.Person.from_dict([("name", "Bob")])
+except TypeError as err:
+    print("caught: %s" % (err,))
@@ -0,0 +1,97 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package directives tests the //gopy: directives annotating the bindings of
+// the declarations.
+package directives
+
+// Limit is the maximum size of a Rect.
+//
+//gopy:name LIMIT
+const Limit = 100
+
+// Verbose is not bound.
+//
+//gopy:skip
+var Verbose = false
+
+// Rect is a rectangle.
+type Rect struct {
+	W, H int
+
+	// Tag is exposed as label.
+	//
+	//gopy:name label
+	Tag string
+
+	// Cache is not bound.
+	//
+	//gopy:skip
+	Cache []int
+}
+
+// NewRect is the only constructor of Rect.
+//
+//gopy:ctor
+func NewRect(w, h int) Rect {
+	return Rect{W: w, H: h, Tag: "rect"}
+}
+
+// Square returns a square of side n.
+func Square(n int) Rect {
+	return Rect{W: n, H: n, Tag: "square"}
+}
+
+// Area is the area of the rectangle.
+//
+//gopy:property
+func (r *Rect) Area() int {
+	return r.W * r.H
+}
+
+// Scale scales the rectangle by n.
+//
+//gopy:name scale_by
+func (r *Rect) Scale(n int) {
+	r.W *= n
+	r.H *= n
+}
+
+// Debug is not bound.
+//
+//gopy:skip
+func (r *Rect) Debug() string {
+	return "debug"
+}
+
+// Count returns the number of integers up to n which are multiples of k, with
+// the GIL released.
+//
+//gopy:nogil
+func Count(n, k int) int {
+	c := 0
+	for i := 1; i <= n; i++ {
+		if i%k == 0 {
+			c++
+		}
+	}
+	return c
+}
+
+// Hidden is not bound, nor are the declarations using it.
+//
+//gopy:skip
+type Hidden struct{}
+
+// Reveal is not bound, as it uses Hidden.
+func Reveal() Hidden {
+	return Hidden{}
+}
+
+// Unknown has an unknown directive, which is reported as a warning.
+//
+//gopy:unknown
+func Unknown() int {
+	return 42
+}
@@ -0,0 +1,32 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import directives
+
+print("directives.LIMIT = %s" % (directives.LIMIT,))
+
+r = directives.NewRect(2, 3)
+print("r.W, r.H = %s, %s" % (r.W, r.H))
+print("r.label = %r" % (r.label,))
+print("r.Area = %s" % (r.Area,))
+r.scale_by(2)
+print("r.Area = %s" % (r.Area,))
+try:
+    r.Area = 1
+except (AttributeError, TypeError):
+    print("caught: r.Area is read-only")
+print("directives.Rect.Area.__doc__ = %r" % (directives.Rect.Area.__doc__,))
+print("r.to_dict() = %s" % (sorted(r.to_dict().items()),))
+print("directives.Square(4).Area = %s" % (directives.Square(4).Area,))
+
+print("directives.Count(100, 7) = %s" % (directives.Count(100, 7),))
+print("directives.Unknown() = %s" % (directives.Unknown(),))
+
+for name in ["Limit", "Verbose", "GetVerbose", "Hidden", "Reveal"]:
+    print("hasattr(directives, %r) = %s" % (name, hasattr(directives, name)))
+for name in ["Tag", "Cache", "Debug", "Scale"]:
+    print("hasattr(r, %r) = %s" % (name, hasattr(r, name)))
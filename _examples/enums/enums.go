@@ -0,0 +1,78 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package enums tests typed constant groups exposed as enums.
+package enums
+
+import "strings"
+
+// Color is a color of the rainbow.
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+// String implements fmt.Stringer.
+func (c Color) String() string {
+	switch c {
+	case Red:
+		return "RED"
+	case Green:
+		return "GREEN"
+	case Blue:
+		return "BLUE"
+	}
+	return "Color(?)"
+}
+
+// Perm is a set of permissions.
+type Perm uint8
+
+const (
+	Read Perm = 1 << iota
+	Write
+	Exec
+)
+
+// Next returns the color following c.
+func Next(c Color) Color {
+	return (c + 1) % 3
+}
+
+// Name returns the name of the color c.
+func Name(c Color) string {
+	return c.String()
+}
+
+// Invalid returns a color which is not part of the enum.
+func Invalid() Color {
+	return 42
+}
+
+// All returns all the permissions.
+func All() Perm {
+	return Read | Write | Exec
+}
+
+// Has reports whether p contains q.
+func Has(p, q Perm) bool {
+	return p&q == q
+}
+
+// Describe returns a description of the permissions p.
+func Describe(p Perm) string {
+	var s []string
+	for _, v := range []struct {
+		p Perm
+		n string
+	}{{Read, "r"}, {Write, "w"}, {Exec, "x"}} {
+		if p&v.p != 0 {
+			s = append(s, v.n)
+		}
+	}
+	return strings.Join(s, "")
+}
@@ -0,0 +1,37 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import enums
+
+print("members:", [m.name for m in enums.Color])
+print("enums.Red = %r" % (enums.Red,))
+print("enums.Color.GREEN = %r" % (enums.Color.GREEN,))
+print("enums.Blue is enums.Color.BLUE:", enums.Blue is enums.Color.BLUE)
+print("enums.Color(2) = %s" % (enums.Color(2),))
+print("enums.Red == 0:", enums.Red == 0)
+print("isinstance(enums.Green, int):", isinstance(enums.Green, int))
+
+print("enums.Next(enums.Red) = %r" % (enums.Next(enums.Red),))
+print("enums.Next(enums.Blue) = %r" % (enums.Next(enums.Blue),))
+print("enums.Next(1) = %r" % (enums.Next(1),))
+print("enums.Name(enums.Blue) = %s" % (enums.Name(enums.Blue),))
+print("enums.Invalid() = %r" % (enums.Invalid(),))
+
+try:
+    enums.Color(42)
+    print("*ERROR* no exception raised!")
+except ValueError:
+    print("caught: invalid Color")
+
+print("perms:", [m.name for m in enums.Perm])
+print("enums.Exec = %r" % (enums.Exec,))
+print("enums.Exec.value = %d" % (enums.Exec.value,))
+print("enums.Describe(enums.Read | enums.Exec) = %s" % (enums.Describe(enums.Read | enums.Exec),))
+print("enums.Has(enums.All(), enums.Write) =", enums.Has(enums.All(), enums.Write))
+print("int(enums.All()) = %d" % (int(enums.All()),))
+print("enums.All() = %r" % (enums.All(),))
+print("enums.Perm(5).name = %s" % (enums.Perm(5).name,))
@@ -0,0 +1,63 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package generics tests the binding of instantiated generic types.
+package generics
+
+// Stack is a LIFO stack of values.
+//
+//gopy:instantiate Stack[int]
+//gopy:instantiate Stack[string]
+//gopy:instantiate Stack[[]int]
+//gopy:instantiate Stack[map[string]int]
+type Stack[T any] struct {
+	Items []T // values of the stack, from bottom to top
+}
+
+// Push adds v on top of the stack.
+func (s *Stack[T]) Push(v T) {
+	s.Items = append(s.Items, v)
+}
+
+// Pop removes and returns the value on top of the stack.
+func (s *Stack[T]) Pop() T {
+	v := s.Items[len(s.Items)-1]
+	s.Items = s.Items[:len(s.Items)-1]
+	return v
+}
+
+// Len returns the number of values in the stack.
+func (s *Stack[T]) Len() int {
+	return len(s.Items)
+}
+
+// Pair holds a key and its value.
+//
+//gopy:instantiate Pair[string, float64]
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Queue is never instantiated.
+type Queue[T any] struct {
+	items []T
+}
+
+// Sum returns the sum of the values of s.
+func Sum(s Stack[int]) int {
+	sum := 0
+	for _, v := range s.Items {
+		sum += v
+	}
+	return sum
+}
+
+// Max can not be bound.
+func Max[T int | float64](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,38 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import generics
+
+s = generics.StackInt()
+s.Push(1)
+s.Push(2)
+s.Push(39)
+print("s.Len() = %d" % (s.Len(),))
+print("generics.Sum(s) = %d" % (generics.Sum(s),))
+print("s.Pop() = %d" % (s.Pop(),))
+print("s.Items = %s" % (list(s.Items),))
+
+ss = generics.StackString()
+ss.Push("hello")
+ss.Push("world")
+print("ss.Pop() = %s" % (ss.Pop(),))
+print("ss.Len() = %d" % (ss.Len(),))
+print("doc(StackString.Push) = %s" % (generics.StackString.Push.__doc__.splitlines()[-1],))
+
+p = generics.PairStringFloat64(Key="pi", Value=3.14)
+print("p.Key = %s, p.Value = %s" % (p.Key, p.Value))
+
+sl = generics.StackSliceInt.from_dict({"Items": [[1, 2], [3]]})
+print("sl.Len() = %d" % (sl.Len(),))
+print("sl.Pop() = %s" % (list(sl.Pop()),))
+
+sm = generics.StackMapStringInt.from_dict({"Items": [{"a": 1}]})
+print("sm.Len() = %d" % (sm.Len(),))
+print("sm.to_dict() = %s" % (sm.to_dict(),))
+
+for name in ("Stack", "Pair", "Queue", "Max"):
+    print("hasattr(generics, %r) = %s" % (name, hasattr(generics, name)))
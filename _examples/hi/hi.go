@@ -45,8 +45,9 @@ func Add(i, j int) int {
 
 // Person is a simple struct
 type Person struct {
+	// Name is the first name of the person.
 	Name string
-	Age  int
+	Age  int // age in years
 }
 
 // NewPerson creates a new Person value
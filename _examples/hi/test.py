@@ -9,6 +9,8 @@ print hi.__doc__
 
 print "--- hi.GetUniverse():", hi.GetUniverse()
 print "--- hi.GetVersion():", hi.GetVersion()
+print "--- hi.Universe:", hi.Universe
+print "--- hi.Version:", hi.Version
 
 print "--- hi.GetDebug():",hi.GetDebug()
 print "--- hi.SetDebug(true)"
@@ -25,6 +27,22 @@ print "--- hi.SetAnon(hi.NewPerson('you', 24))..."
 hi.SetAnon(anon)
 print "--- hi.GetAnon():",hi.GetAnon()
 
+print "--- hi.Debug:",hi.Debug
+print "--- hi.Debug = True"
+hi.Debug = True
+print "--- hi.GetDebug():",hi.GetDebug()
+hi.Debug = False
+print "--- hi.Debug:",hi.Debug
+print "--- hi.Anon.Age = 25"
+hi.Anon.Age = 25
+print "--- hi.Anon:",hi.Anon
+print "--- hi.IntSlice[0] = 3"
+hi.IntSlice[0] = 3
+print "--- hi.GetIntSlice():",hi.GetIntSlice()
+hi.IntSlice[0] = 1
+hi.Anon = hi.NewPerson('you', 24)
+print "--- hi.Anon:",hi.Anon
+
 print "--- doc(hi.Hi)..."
 print hi.Hi.__doc__
 
@@ -49,6 +67,12 @@ print hi.Concat("4","2")
 print "--- doc(hi.Person):"
 print hi.Person.__doc__
 
+print "--- doc(hi.Person.Name):"
+print hi.Person.Name.__doc__
+
+print "--- doc(hi.Person.Age):"
+print hi.Person.Age.__doc__
+
 print "--- p = hi.Person()..."
 p = hi.Person()
 print dir(p)
@@ -0,0 +1,94 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package naming tests the python names of the declarations with the pep8
+// naming.
+package naming
+
+import "strings"
+
+// MaxConns is the default maximum number of connections of a Server.
+const MaxConns = 16
+
+// TimeoutMillis is the timeout of the requests, in milliseconds.
+//
+//gopy:name TIMEOUT
+const TimeoutMillis = 500
+
+// DefaultHost is the host of the servers made by NewHTTPServer.
+var DefaultHost = "localhost"
+
+// None collides with a python keyword with the go naming only.
+var None = "nothing"
+
+// Server is a server listening on a host.
+type Server struct {
+	HostName string // name of the host
+	MaxConns int    `py:"maxconn"`
+}
+
+// NewHTTPServer returns a new server listening on host.
+func NewHTTPServer(host string) Server {
+	return Server{HostName: host, MaxConns: MaxConns}
+}
+
+// SetMaxConns sets the maximum number of connections of the server.
+func (s *Server) SetMaxConns(n int) {
+	s.MaxConns = n
+}
+
+// URL returns the URL of the server.
+//
+//gopy:name address
+func (s *Server) URL() string {
+	return "http://" + s.HostName
+}
+
+// ToDict is not bound with the pep8 naming, as to_dict is already a method of
+// the python classes.
+func (s *Server) ToDict() string {
+	return "host=" + s.HostName
+}
+
+// Level is a logging level.
+type Level int
+
+// IsVerbose reports whether the level is a verbose one.
+func (l Level) IsVerbose() bool {
+	return l > 1
+}
+
+// Print returns the message s as printed by a server.
+func Print(s string) string {
+	return "> " + s
+}
+
+// Len returns the length of s.
+//
+//gopy:name length
+func Len(s string) int {
+	return len(s)
+}
+
+// ToUpper returns s in upper case.
+func ToUpper(s string) string {
+	return strings.ToUpper(s)
+}
+
+// HTTPGet returns the GET request of the URL u.
+func HTTPGet(u string) string {
+	return "GET " + u
+}
+
+// HttpGet is not bound with the pep8 naming, as http_get is already the
+// name of HTTPGet.
+func HttpGet(u string) string {
+	return "get " + u
+}
+
+// GetDefaultHost is not bound, as its name is already the one of the getter
+// of DefaultHost.
+func GetDefaultHost() string {
+	return DefaultHost
+}
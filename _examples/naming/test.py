@@ -0,0 +1,36 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import naming
+
+print("naming.MAX_CONNS = %s" % (naming.MAX_CONNS,))
+print("naming.TIMEOUT = %s" % (naming.TIMEOUT,))
+print("naming.get_timeout() = %s" % (naming.get_timeout(),))
+print("naming.default_host = %r" % (naming.default_host,))
+naming.default_host = "example.com"
+print("naming.get_default_host() = %r" % (naming.get_default_host(),))
+print("naming.none = %r" % (naming.none,))
+
+s = naming.new_http_server(naming.default_host)
+print("s.host_name = %r" % (s.host_name,))
+print("s.maxconn = %s" % (s.maxconn,))
+s.set_max_conns(4)
+print("s.maxconn = %s" % (s.maxconn,))
+print("s.address() = %r" % (s.address(),))
+print("s.to_dict() = %s" % (sorted(s.to_dict().items()),))
+
+s = naming.Server(host_name="golang.org", maxconn=2)
+print("s.address() = %r" % (s.address(),))
+
+print("naming.print_('hello') = %r" % (naming.print_("hello"),))
+print("naming.length('hello') = %s" % (naming.length("hello"),))
+print("naming.to_upper('hello') = %r" % (naming.to_upper("hello"),))
+print("naming.Level(2).is_verbose() = %s" % (naming.Level(2).is_verbose(),))
+print("naming.http_get('/') = %r" % (naming.http_get("/"),))
+
+for name in ("Print", "Len", "NewHTTPServer", "MaxConns", "GetDefaultHost", "HttpGet"):
+    print("hasattr(naming, %r) = %s" % (name, hasattr(naming, name)))
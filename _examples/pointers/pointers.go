@@ -2,6 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// Package pointers tests the pointers to structs, exposed to python as the
+// struct values they point to. Other pointers are skipped.
 package pointers
 
 //type SPtr *S
@@ -10,11 +12,21 @@ type S struct {
 	Value int
 }
 
+// NewS returns a pointer to a new S.
+func NewS(v int) *S {
+	return &S{Value: v}
+}
+
 // Inc increments the value of s
 func Inc(s *S) {
 	s.Value++
 }
 
+// Add adds the value of t to the value of s.
+func (s *S) Add(t *S) {
+	s.Value += t.Value
+}
+
 type MyInt int
 
 // IncInt increments an integer
@@ -26,3 +38,12 @@ func IncMyInt(i *MyInt) {
 func IncInt(i *int) {
 	(*i)++
 }
+
+// Total returns the sum of the values of ss.
+func Total(ss []*S) int {
+	n := 0
+	for _, s := range ss {
+		n += s.Value
+	}
+	return n
+}
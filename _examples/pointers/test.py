@@ -9,8 +9,19 @@ import pointers
 
 print("s = pointers.S(2)")
 s = pointers.S(2)
-print("s = %s" % (s,))
 print("s.Value = %s" % (s.Value,))
 
 print("pointers.Inc(s)")
+pointers.Inc(s)
 print("s.Value = %s" % (s.Value,))
+
+t = pointers.NewS(10)
+print("t.Value = %s" % (t.Value,))
+print("s.Add(t)")
+s.Add(t)
+print("s.Value = %s" % (s.Value,))
+pointers.Inc(t)
+print("t.Value = %s" % (t.Value,))
+
+for name in ["IncInt", "IncMyInt", "Total"]:
+    print("hasattr(pointers, %r) = %s" % (name, hasattr(pointers, name)))
@@ -0,0 +1,59 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package shim tests the python layer over the extension module of a Go
+// package.
+package shim
+
+import (
+	"errors"
+	"strconv"
+)
+
+// MaxSpeed is the maximum speed of a Vehicle.
+const MaxSpeed = 120
+
+// DefaultOwner is the owner of the vehicles made by NewVehicle.
+var DefaultOwner = "nobody"
+
+// Vehicle is a vehicle driven at some speed.
+type Vehicle struct {
+	Owner string // owner of the vehicle
+
+	// Speed is the current speed of the vehicle.
+	Speed int `py:"speed,readonly"`
+
+	// HTTPEndpoint is where the vehicle reports its position.
+	HTTPEndpoint string
+}
+
+// NewVehicle returns a vehicle owned by DefaultOwner.
+func NewVehicle() Vehicle {
+	return Vehicle{Owner: DefaultOwner}
+}
+
+// Accelerate increases the speed of the vehicle by dv.
+func (v *Vehicle) Accelerate(dv int) error {
+	if v.Speed+dv > MaxSpeed {
+		return errors.New("too fast")
+	}
+	v.Speed += dv
+	return nil
+}
+
+// Convoy is a pair of vehicles.
+type Convoy struct {
+	Lead     Vehicle
+	Follower Vehicle
+}
+
+// TotalSpeed returns the sum of the speeds of a and b.
+func TotalSpeed(a, b Vehicle) int {
+	return a.Speed + b.Speed
+}
+
+// ParseSpeed parses the speed s.
+func ParseSpeed(s string) (int, error) {
+	return strconv.Atoi(s)
+}
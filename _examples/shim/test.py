@@ -0,0 +1,53 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import shim
+
+print("shim.__doc__ = %r" % (shim.__doc__,))
+print("shim.MAX_SPEED = %s" % (shim.MAX_SPEED,))
+print("shim.default_owner = %r" % (shim.default_owner,))
+shim.default_owner = "alice"
+print("shim.get_default_owner() = %r" % (shim.get_default_owner(),))
+
+v = shim.new_vehicle()
+print("type(v) = %s" % (type(v).__name__,))
+print("v.owner = %r" % (v.owner,))
+v.http_endpoint = "http://alice"
+print("v.http_endpoint = %r" % (v.http_endpoint,))
+print("shim.Vehicle.accelerate.__doc__ = %r" % (shim.Vehicle.accelerate.__doc__,))
+v.accelerate(100)
+print("v.speed = %s" % (v.speed,))
+
+try:
+    v.accelerate(100)
+except shim.GoError as err:
+    print("caught GoError: %s (RuntimeError: %s)" % (err, isinstance(err, RuntimeError)))
+
+try:
+    v.speed = 3
+except AttributeError:
+    print("caught AttributeError: v.speed is read-only")
+
+w = shim.Vehicle(owner="bob")
+print("w.owner = %r, w.speed = %s" % (w.owner, w.speed))
+print("shim.total_speed(v, w) = %s" % (shim.total_speed(v, w),))
+
+c = shim.Convoy(lead=v)
+print("type(c.lead) = %s" % (type(c.lead).__name__,))
+print("c.lead.owner = %r" % (c.lead.owner,))
+c.follower = w
+print("c.follower.owner = %r" % (c.follower.owner,))
+
+print("shim.parse_speed('42') = %s" % (shim.parse_speed("42"),))
+try:
+    shim.parse_speed("fast")
+except shim.GoError as err:
+    print("caught GoError: %s" % (err,))
+
+d = shim.Vehicle.from_dict({"owner": "carol", "http_endpoint": "http://carol"})
+print("type(d) = %s" % (type(d).__name__,))
+print("sorted(d.to_dict().items()) = %s" % (sorted(d.to_dict().items()),))
@@ -7,3 +7,13 @@ package simple
 
 // Func is a simple func
 func Func() {}
+
+// Answer ignores its unnamed parameters.
+func Answer(int, string) int {
+	return 42
+}
+
+// Second returns its second parameter, ignoring the blank ones.
+func Second(_ int, b int, _ int) int {
+	return b
+}
@@ -15,3 +15,5 @@ fct = pkg.Func
 print("fct()...")
 fct()
 
+print("pkg.Answer(1, 'a') = %d" % (pkg.Answer(1, "a"),))
+print("pkg.Second(1, 2, 3) = %d" % (pkg.Second(1, 2, 3),))
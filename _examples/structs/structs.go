@@ -23,3 +23,11 @@ type S2 struct {
 	Public  int
 	private int
 }
+
+// S3 exposes its fields under python-specific names.
+type S3 struct {
+	ID     int    `py:"id,readonly"`
+	Name   string `py:"name"`
+	Secret string `py:"-"`
+	Public int
+}
@@ -53,3 +53,29 @@ try:
 except Exception, err:
     print("caught error: %s" % (err,))
     pass
+
+print("s3 = structs.S3(id=1, name='x', Public=2)")
+s3 = structs.S3(id=1, name='x', Public=2)
+print("s3 = %s" % (s3,))
+print("s3.id = %s" % (s3.id,))
+print("s3.name = %s" % (s3.name,))
+print("s3.to_dict() = %s" % (sorted(s3.to_dict().items()),))
+
+try:
+    s3.id = 42
+except Exception as err:
+    print("caught error: %s" % (err,))
+    pass
+
+for attr in ("ID", "Name", "Secret"):
+    try:
+        print("s3.%s = %s" % (attr, getattr(s3, attr)))
+    except Exception as err:
+        print("caught error: %s" % (err,))
+        pass
+
+try:
+    s3 = structs.S3(Secret="xxx")
+except Exception as err:
+    print("caught error: %s" % (err,))
+    pass
@@ -0,0 +1,20 @@
+# Copyright 2015 The go-python Authors.  All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+## py2/py3 compat
+from __future__ import print_function
+
+import unsupported
+
+print("unsupported.Add(1, 2) = %d" % (unsupported.Add(1, 2),))
+print("unsupported.Count = %d" % (unsupported.Count,))
+
+for name in ("Events", "Pipe", "Registry", "Send", "Split"):
+    print("hasattr(unsupported, %r) = %s" % (name, hasattr(unsupported, name)))
+
+j = unsupported.NewJob("build", 3)
+print("j = %s" % (j,))
+print("j.Name = %s, j.ID = %d" % (j.Name, j.ID))
+print("hasattr(j, 'Done') = %s" % (hasattr(j, "Done"),))
+print("hasattr(j, 'Wait') = %s" % (hasattr(j, "Wait"),))
@@ -0,0 +1,62 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package unsupported tests that declarations which can not be exposed to
+// python are skipped, while the rest of the package is still bound.
+package unsupported
+
+// Events can not be exposed to python.
+var Events = make(chan int)
+
+// Count can be exposed to python.
+var Count = 42
+
+// Pipe can not be exposed to python.
+type Pipe chan string
+
+// Registry can not be exposed to python.
+type Registry map[string]int
+
+// Send can not be exposed to python.
+func Send(c chan int, v int) {
+	c <- v
+}
+
+// Split can not be exposed to python.
+func Split(s string) (string, string, string) {
+	return s, s, s
+}
+
+// Add can be exposed to python.
+func Add(a, b int) int {
+	return a + b
+}
+
+// Job has a field of an unsupported type.
+type Job struct {
+	Name string
+	Done chan bool
+	ID   int
+}
+
+// NewJob returns a new job.
+func NewJob(name string, id int) Job {
+	return Job{Name: name, ID: id}
+}
+
+// Wait can not be exposed to python.
+func (j *Job) Wait() <-chan bool {
+	return j.Done
+}
+
+// String can be exposed to python.
+func (j Job) String() string {
+	return j.Name
+}
+
+// Waiter has a method of an unsupported type.
+type Waiter interface {
+	Name() string
+	Wait() <-chan bool
+}
@@ -28,12 +28,20 @@ func (list ErrorList) Error() string {
 
 // GenCPython generates a (C)Python package from a Go package
 func GenCPython(w io.Writer, fset *token.FileSet, pkg *Package, lang int) error {
+	return GenCPythonModule(w, fset, pkg, lang, pkg.Name())
+}
+
+// GenCPythonModule is like GenCPython, but gives the extension module the
+// given name rather than the name of the Go package (e.g. "_hi" for the
+// module wrapped by the python layer of GenShim).
+func GenCPythonModule(w io.Writer, fset *token.FileSet, pkg *Package, lang int, name string) error {
 	gen := &cpyGen{
 		decl: &printer{buf: new(bytes.Buffer), indentEach: []byte("\t")},
 		impl: &printer{buf: new(bytes.Buffer), indentEach: []byte("\t")},
 		fset: fset,
 		pkg:  pkg,
 		lang: lang,
+		name: name,
 	}
 	err := gen.gen()
 	if err != nil {
@@ -53,14 +61,25 @@ func GenCPython(w io.Writer, fset *token.FileSet, pkg *Package, lang int) error
 	return err
 }
 
-// GenGo generates a cgo package from a Go package
-func GenGo(w io.Writer, fset *token.FileSet, pkg *Package, lang int) error {
+// PyConfig describes how to build against a given python interpreter.
+type PyConfig struct {
+	Version   int      // major version of python (2 or 3)
+	CFlags    []string // flags to compile against the python headers
+	LdFlags   []string // flags to link against the python library
+	ExtSuffix string   // file name suffix of extension modules (e.g. ".so")
+}
+
+// GenGo generates a cgo package from a Go package.
+// The cgo package is built against the python described by cfg or, if cfg is
+// nil, against the python found by pkg-config.
+func GenGo(w io.Writer, fset *token.FileSet, pkg *Package, lang int, cfg *PyConfig) error {
 	buf := new(bytes.Buffer)
 	gen := &goGen{
 		printer: &printer{buf: buf, indentEach: []byte("\t")},
 		fset:    fset,
 		pkg:     pkg,
 		lang:    lang,
+		pycfg:   cfg,
 	}
 	err := gen.gen()
 	if err != nil {
@@ -0,0 +1,268 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/doc"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the generated code")
+
+// goldenNaming lists the _examples packages whose golden files are generated
+// with another naming than GoNaming.
+var goldenNaming = map[string]Naming{
+	"naming": PEP8Naming,
+}
+
+// goldenPyConfig is the python configuration used to generate the golden
+// files, so they do not depend on the python (or pkg-config) installed.
+var goldenPyConfig = &PyConfig{
+	Version:   2,
+	CFlags:    []string{"-I/usr/include/python2.7"},
+	LdFlags:   []string{"-lpython2.7"},
+	ExtSuffix: ".so",
+}
+
+// generated is the code generated for a package, stored in a golden file
+// with the given extension.
+type generated struct {
+	ext  string
+	code []byte
+}
+
+// genExample generates the C and Go code of the bindings, the C API, the cffi
+// module, the Cython declarations and the python layer of the _examples
+// package in dir, and returns them with the loaded package.
+func genExample(t *testing.T, dir string) (*packages.Package, []generated) {
+	fset := token.NewFileSet()
+	conf := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports |
+			packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Fset: fset,
+	}
+	pkgs, err := packages.Load(conf, "../_examples/"+dir)
+	if err != nil {
+		t.Fatalf("[%s]: could not load package: %v", dir, err)
+	}
+	if len(pkgs) != 1 || len(pkgs[0].Errors) > 0 {
+		t.Fatalf("[%s]: could not load package: %v", dir, pkgs[0].Errors)
+	}
+	pkg := pkgs[0]
+
+	// parse the original files, rather than the cgo-processed ones.
+	var files []*ast.File
+	for _, fname := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, fname, nil, parser.ParseComments)
+		if err != nil {
+			t.Fatalf("[%s]: could not parse %s: %v", dir, fname, err)
+		}
+		files = append(files, f)
+	}
+
+	pkgdoc, err := doc.NewFromFiles(fset, files, pkg.PkgPath, doc.PreserveAST)
+	if err != nil {
+		t.Fatalf("[%s]: could not extract documentation: %v", dir, err)
+	}
+
+	var cfg *Config
+	if f, err := os.Open(filepath.Join("../_examples", dir, ConfigFile)); err == nil {
+		cfg, err = ReadConfig(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("[%s]: could not read configuration: %v", dir, err)
+		}
+	}
+
+	p, err := NewPackageConfig(fset, pkg.Types, pkgdoc, cfg)
+	if err != nil {
+		t.Fatalf("[%s]: could not process package: %v", dir, err)
+	}
+	if n, ok := goldenNaming[dir]; ok {
+		p.SetNaming(n)
+	}
+
+	c := new(bytes.Buffer)
+	err = GenCPython(c, fset, p, 2)
+	if err != nil {
+		t.Fatalf("[%s]: could not generate C code: %v", dir, err)
+	}
+
+	g := new(bytes.Buffer)
+	err = GenGo(g, fset, p, 2, goldenPyConfig)
+	if err != nil {
+		t.Fatalf("[%s]: could not generate Go code: %v", dir, err)
+	}
+
+	a := new(bytes.Buffer)
+	err = GenC(a, fset, p)
+	if err != nil {
+		t.Fatalf("[%s]: could not generate C API: %v", dir, err)
+	}
+
+	py := new(bytes.Buffer)
+	err = GenCFFI(py, fset, p)
+	if err != nil {
+		t.Fatalf("[%s]: could not generate cffi module: %v", dir, err)
+	}
+
+	pxd := new(bytes.Buffer)
+	err = GenCython(pxd, fset, p)
+	if err != nil {
+		t.Fatalf("[%s]: could not generate Cython declarations: %v", dir, err)
+	}
+
+	shim := new(bytes.Buffer)
+	err = GenShim(shim, fset, p)
+	if err != nil {
+		t.Fatalf("[%s]: could not generate python layer: %v", dir, err)
+	}
+
+	return pkg, []generated{
+		{".c", c.Bytes()},
+		{".go", g.Bytes()},
+		{".capi", a.Bytes()},
+		{".py", py.Bytes()},
+		{".pxd", pxd.Bytes()},
+		{".shim.py", shim.Bytes()},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	dirs, err := ioutil.ReadDir("../_examples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := importer.ForCompiler(token.NewFileSet(), "source", nil)
+
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		name := dir.Name()
+		pkg, gens := genExample(t, name)
+
+		// generate a second time, to check the output is reproducible.
+		_, again := genExample(t, name)
+		for i, gen := range again {
+			if !bytes.Equal(gens[i].code, gen.code) {
+				t.Errorf("[%s]: generated %s code is not deterministic", name, gen.ext)
+			}
+		}
+
+		for _, gen := range gens {
+			if gen.ext == ".go" || gen.ext == ".capi" {
+				err := typeCheck(newPkgImporter(pkg.Types, src), gen.code)
+				if err != nil {
+					t.Errorf("[%s]: generated %s code does not type-check: %v", name, gen.ext, err)
+				}
+			}
+
+			fname := filepath.Join("testdata", name+gen.ext+".golden")
+			if *update {
+				err = ioutil.WriteFile(fname, gen.code, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+
+			want, err := ioutil.ReadFile(fname)
+			if err != nil {
+				t.Errorf("[%s]: could not read golden file (run with -update to create it): %v", name, err)
+				continue
+			}
+			if !bytes.Equal(gen.code, want) {
+				t.Errorf("[%s]: generated code differs from %s (run with -update to accept it):\n%s",
+					name, fname, diff(want, gen.code),
+				)
+			}
+		}
+	}
+}
+
+// pkgImporter imports a type-checked package and its dependencies, and the
+// other packages with a fallback importer.
+type pkgImporter struct {
+	pkgs     map[string]*types.Package
+	fallback types.Importer
+}
+
+func newPkgImporter(pkg *types.Package, fallback types.Importer) *pkgImporter {
+	imp := &pkgImporter{
+		pkgs:     make(map[string]*types.Package),
+		fallback: fallback,
+	}
+	imp.add(pkg)
+	return imp
+}
+
+func (imp *pkgImporter) add(pkg *types.Package) {
+	if _, dup := imp.pkgs[pkg.Path()]; dup || !pkg.Complete() {
+		return
+	}
+	imp.pkgs[pkg.Path()] = pkg
+	for _, dep := range pkg.Imports() {
+		imp.add(dep)
+	}
+}
+
+func (imp *pkgImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := imp.pkgs[path]; ok {
+		return pkg, nil
+	}
+	return imp.fallback.Import(path)
+}
+
+// typeCheck parses and type-checks the generated cgo package code.
+// The references to the C declarations are not checked.
+func typeCheck(imp types.Importer, code []byte) error {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "main.go", code, 0)
+	if err != nil {
+		return err
+	}
+	conf := types.Config{
+		FakeImportC: true,
+		Importer:    imp,
+	}
+	_, err = conf.Check("main", fset, []*ast.File{f}, nil)
+	return err
+}
+
+// diff returns the differences between want and got, if the diff command is
+// available.
+func diff(want, got []byte) string {
+	bin, err := exec.LookPath("diff")
+	if err != nil {
+		return ""
+	}
+	dir, err := ioutil.TempDir("", "gopy-golden-")
+	if err != nil {
+		return ""
+	}
+	defer os.RemoveAll(dir)
+
+	fwant := filepath.Join(dir, "want")
+	fgot := filepath.Join(dir, "got")
+	if ioutil.WriteFile(fwant, want, 0644) != nil || ioutil.WriteFile(fgot, got, 0644) != nil {
+		return ""
+	}
+	out, _ := exec.Command(bin, "-u", fwant, fgot).CombinedOutput()
+	return string(out)
+}
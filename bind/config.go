@@ -0,0 +1,261 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ConfigFile is the name of the file holding the binding configuration of a
+// package, next to its sources.
+const ConfigFile = "gopy.json"
+
+// Config is the binding configuration of a package.
+// Declarations are referred to by their name, or Type.Name for the fields
+// and methods of a type.
+type Config struct {
+	// Naming is the naming convention of the python names: "go" (the
+	// default) or "pep8".
+	Naming string `json:"naming,omitempty"`
+
+	// Include lists the package-level declarations to bind.
+	// When it is empty, all the exported declarations are bound.
+	Include []string `json:"include,omitempty"`
+
+	// Exclude lists the declarations, fields and methods not to bind.
+	// The declarations using an excluded type are not bound either.
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Names maps declarations, fields and methods to their python names,
+	// overriding the naming, the //gopy:name directives and the py struct
+	// tags.
+	Names map[string]string `json:"names,omitempty"`
+
+	// Ctors maps struct types to the functions exposed as their
+	// constructors.
+	// By default, all the functions returning a struct type are its
+	// constructors.
+	Ctors map[string][]string `json:"ctors,omitempty"`
+
+	// Enums maps named integer types to whether their constants are exposed
+	// as an enum class, even when the type has a single constant.
+	Enums map[string]bool `json:"enums,omitempty"`
+
+	// GIL maps functions and methods to whether the GIL is held ("hold",
+	// the default) or released ("release") while their Go code runs.
+	// The "*" entry sets the policy of the functions and methods which are
+	// not listed.
+	GIL map[string]string `json:"gil,omitempty"`
+
+	// Packages lists the paths of other packages bound alongside the
+	// package, each in its own module and with its own configuration.
+	// Relative paths ("./x" or "../x") are resolved from the directory of
+	// the configuration.
+	Packages []string `json:"packages,omitempty"`
+}
+
+// ReadConfig reads a JSON binding configuration from r.
+func ReadConfig(r io.Reader) (*Config, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var cfg Config
+	err := dec.Decode(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseNaming(cfg.Naming); err != nil {
+		return nil, err
+	}
+	for name, alias := range cfg.Names {
+		if !pyIdent.MatchString(alias) || pyKeywords[alias] {
+			return nil, fmt.Errorf("gopy: invalid python name %q for %s", alias, name)
+		}
+	}
+	for _, path := range cfg.Packages {
+		if strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("gopy: invalid empty package path")
+		}
+	}
+	for name, policy := range cfg.GIL {
+		if policy != "hold" && policy != "release" {
+			return nil, fmt.Errorf("gopy: invalid GIL policy %q for %s (expected hold or release)", policy, name)
+		}
+	}
+	return &cfg, nil
+}
+
+// addExcluded hides the fields and methods excluded by the configuration or
+// by //gopy:skip directives.
+func (p *Package) addExcluded() {
+	keys := append([]string(nil), p.cfg.Exclude...)
+	for key := range p.dirs.skip {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		switch obj := lookupDecl(p.pkg, key).(type) {
+		case *types.Var:
+			if obj.IsField() {
+				pf := p.fields[obj.Origin()]
+				pf.hidden = true
+				p.fields[obj.Origin()] = pf
+			}
+		case *types.Func:
+			if obj.Type().(*types.Signature).Recv() != nil {
+				p.syms.hidden[obj.Origin()] = true
+			}
+		}
+	}
+}
+
+// excludedType returns the name of a type excluded by the configuration, or
+// by a //gopy:skip directive, which the package-level declaration obj uses, or "" if there is none.
+// The fields and methods of the types are checked by checkMembers.
+func (p *Package) excludedType(obj types.Object) string {
+	typ := obj.Type()
+	if _, ok := obj.(*types.TypeName); ok {
+		typ = typ.Underlying()
+		if _, ok := typ.(*types.Struct); ok {
+			return ""
+		}
+	}
+	return p.usesExcluded(typ)
+}
+
+// usesExcluded returns the name of a type excluded by the configuration, or
+// by a //gopy:skip directive, which typ refers to, or "" if there is none.
+func (p *Package) usesExcluded(typ types.Type) string {
+	switch typ := typ.(type) {
+	case *types.Named:
+		obj := typ.Obj()
+		if obj.Pkg() == p.pkg && p.excluded(obj.Name()) != "" {
+			return obj.Name()
+		}
+		for i := 0; i < typ.TypeArgs().Len(); i++ {
+			if t := p.usesExcluded(typ.TypeArgs().At(i)); t != "" {
+				return t
+			}
+		}
+	case *types.Pointer:
+		return p.usesExcluded(typ.Elem())
+	case *types.Slice:
+		return p.usesExcluded(typ.Elem())
+	case *types.Array:
+		return p.usesExcluded(typ.Elem())
+	case *types.Chan:
+		return p.usesExcluded(typ.Elem())
+	case *types.Map:
+		if t := p.usesExcluded(typ.Key()); t != "" {
+			return t
+		}
+		return p.usesExcluded(typ.Elem())
+	case *types.Signature:
+		if t := p.usesExcluded(typ.Params()); t != "" {
+			return t
+		}
+		return p.usesExcluded(typ.Results())
+	case *types.Tuple:
+		for i := 0; i < typ.Len(); i++ {
+			if t := p.usesExcluded(typ.At(i).Type()); t != "" {
+				return t
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			if t := p.usesExcluded(typ.Field(i).Type()); t != "" {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// included reports whether the package-level declaration name is bound
+// according to the configuration.
+func (cfg *Config) included(name string) bool {
+	if len(cfg.Include) > 0 && !contains(cfg.Include, name) {
+		return false
+	}
+	return !contains(cfg.Exclude, name)
+}
+
+// releaseGIL reports whether the GIL is released while the Go code of the
+// function or method name runs.
+func (cfg *Config) releaseGIL(name string) bool {
+	policy, ok := cfg.GIL[name]
+	if !ok {
+		policy = cfg.GIL["*"]
+	}
+	return policy == "release"
+}
+
+// check returns an error listing the declarations of the configuration
+// which are not in the package pkg.
+func (cfg *Config) check(pkg *types.Package) error {
+	var names []string
+	names = append(names, cfg.Include...)
+	names = append(names, cfg.Exclude...)
+	for name := range cfg.Names {
+		names = append(names, name)
+	}
+	for name, ctors := range cfg.Ctors {
+		names = append(names, name)
+		names = append(names, ctors...)
+	}
+	for name := range cfg.Enums {
+		names = append(names, name)
+	}
+	for name := range cfg.GIL {
+		if name != "*" {
+			names = append(names, name)
+		}
+	}
+
+	var unknown []string
+	for _, name := range names {
+		if lookupDecl(pkg, name) == nil && !contains(unknown, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("gopy: unknown declarations in the configuration of %s: %s",
+		pkg.Path(), strings.Join(unknown, ", "),
+	)
+}
+
+// lookupDecl returns the package-level declaration name, or the field or
+// method Type.Name, of pkg.
+func lookupDecl(pkg *types.Package, name string) types.Object {
+	tname, member := name, ""
+	if i := strings.Index(name, "."); i >= 0 {
+		tname, member = name[:i], name[i+1:]
+	}
+	obj := pkg.Scope().Lookup(tname)
+	if obj == nil || member == "" {
+		return obj
+	}
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil
+	}
+	obj, _, _ = types.LookupFieldOrMethod(types.NewPointer(obj.Type()), false, pkg, member)
+	return obj
+}
+
+// contains reports whether the list of names contains name.
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"go/token"
+	"go/types"
+)
+
+// Status describes how a declaration is exposed to python.
+type Status int
+
+const (
+	Bound   Status = iota // the declaration is exposed to python
+	Partial               // the type is exposed, but some of its fields or methods are not
+	Skipped               // the declaration is not exposed to python
+	Hidden                // the declaration is hidden on purpose, with a py:"-" struct tag or by the configuration
+)
+
+func (s Status) String() string {
+	switch s {
+	case Bound:
+		return "bound"
+	case Partial:
+		return "partial"
+	case Skipped:
+		return "skipped"
+	case Hidden:
+		return "hidden"
+	}
+	return "unknown"
+}
+
+// Decl describes how an exported declaration is exposed to python.
+type Decl struct {
+	Kind   string // const, var, func, type, field or method
+	Name   string // name of the declaration (Type.Name for fields and methods)
+	Status Status
+	Reason string // why the declaration is not (fully) exposed to python
+	Pos    token.Position
+}
+
+// declKind returns the kind of declaration of obj.
+func declKind(obj types.Object) string {
+	switch obj := obj.(type) {
+	case *types.Const:
+		return "const"
+	case *types.Var:
+		if obj.IsField() {
+			return "field"
+		}
+		return "var"
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			return "method"
+		}
+		return "func"
+	case *types.TypeName:
+		return "type"
+	}
+	return "unknown"
+}
+
+type byPos []Decl
+
+func (s byPos) Len() int      { return len(s) }
+func (s byPos) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byPos) Less(i, j int) bool {
+	pi, pj := s[i].Pos, s[j].Pos
+	if pi.Filename != pj.Filename {
+		return pi.Filename < pj.Filename
+	}
+	return pi.Offset < pj.Offset
+}
@@ -0,0 +1,253 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// directivePrefix is the prefix of the comments annotating the bindings of a
+// declaration, in its doc comment, e.g.:
+//
+//	//gopy:skip
+const directivePrefix = "//gopy:"
+
+// directiveKinds maps the known directives to the kinds of declarations
+// they apply to.
+var directiveKinds = map[string][]string{
+	"skip":        {"const", "var", "func", "type", "field", "method"},
+	"name":        {"const", "var", "func", "field", "method"},
+	"nogil":       {"func", "method"},
+	"ctor":        {"func"},
+	"property":    {"method"},
+	"instantiate": {"type"},
+}
+
+// directiveSet collects the bindings requested by the //gopy: directives of
+// a package. The entries of the configuration take precedence over them.
+type directiveSet struct {
+	skip  map[string]bool     // declarations, fields and methods not to bind
+	nogil map[string]bool     // functions and methods releasing the GIL
+	ctors map[string][]string // constructors of the struct types
+	props map[string]bool     // methods exposed as properties
+}
+
+func newDirectiveSet() directiveSet {
+	return directiveSet{
+		skip:  make(map[string]bool),
+		nogil: make(map[string]bool),
+		ctors: make(map[string][]string),
+		props: make(map[string]bool),
+	}
+}
+
+// addDirectives collects the //gopy: directives of the declarations of the
+// package, and of the fields and methods of its types.
+// Unknown or misplaced directives are reported as skipped declarations.
+func (p *Package) addDirectives() {
+	var (
+		funcs  []*doc.Func
+		values []*doc.Value
+	)
+	funcs = append(funcs, p.doc.Funcs...)
+	values = append(values, p.doc.Consts...)
+	values = append(values, p.doc.Vars...)
+	for _, t := range p.doc.Types {
+		funcs = append(funcs, t.Funcs...)
+		values = append(values, t.Consts...)
+		values = append(values, t.Vars...)
+		for _, m := range t.Methods {
+			if m.Decl != nil {
+				p.addDirective("method", t.Name+"."+m.Name, m.Decl.Doc)
+			}
+		}
+		if t.Decl == nil {
+			continue
+		}
+		for _, spec := range t.Decl.Specs {
+			spec, ok := spec.(*ast.TypeSpec)
+			if !ok || spec.Name.Name != t.Name {
+				continue
+			}
+			p.addDirective("type", t.Name, t.Decl.Doc, spec.Doc)
+			st, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				for _, ident := range field.Names {
+					p.addDirective("field", t.Name+"."+ident.Name, field.Doc)
+				}
+			}
+		}
+	}
+	for _, f := range funcs {
+		if f.Decl != nil {
+			p.addDirective("func", f.Name, f.Decl.Doc)
+		}
+	}
+	for _, v := range values {
+		if v.Decl == nil {
+			continue
+		}
+		kind := "const"
+		if v.Decl.Tok == token.VAR {
+			kind = "var"
+		}
+		for _, spec := range v.Decl.Specs {
+			spec := spec.(*ast.ValueSpec)
+			groups := []*ast.CommentGroup{spec.Doc}
+			if len(v.Decl.Specs) == 1 {
+				groups = append(groups, v.Decl.Doc)
+			}
+			for _, ident := range spec.Names {
+				p.addDirective(kind, ident.Name, groups...)
+			}
+		}
+	}
+}
+
+// addDirective applies the //gopy: directives of the comments of the
+// declaration key (Type.Name for fields and methods) of the given kind.
+func (p *Package) addDirective(kind, key string, groups ...*ast.CommentGroup) {
+	seen := make(map[*ast.CommentGroup]bool)
+	for _, g := range groups {
+		if g == nil || seen[g] {
+			continue
+		}
+		seen[g] = true
+		for _, c := range g.List {
+			if !strings.HasPrefix(c.Text, directivePrefix) {
+				continue
+			}
+			name, arg := c.Text[len(directivePrefix):], ""
+			if i := strings.IndexAny(name, " \t"); i >= 0 {
+				name, arg = name[:i], strings.TrimSpace(name[i:])
+			}
+			if err := p.applyDirective(kind, key, name, arg); err != nil {
+				p.addDeclAt(c.Pos(), kind, directivePrefix+name, Skipped,
+					fmt.Sprintf("%v for %s", err, key),
+				)
+			}
+		}
+	}
+}
+
+// applyDirective applies the directive name, with the argument arg, of the
+// declaration key of the given kind.
+func (p *Package) applyDirective(kind, key, name, arg string) error {
+	kinds, ok := directiveKinds[name]
+	if !ok {
+		return fmt.Errorf("unknown directive")
+	}
+	if !contains(kinds, kind) {
+		return fmt.Errorf("directive not supported on a %s", kind)
+	}
+	if arg != "" && name != "name" && name != "instantiate" {
+		return fmt.Errorf("unexpected argument %q", arg)
+	}
+
+	switch name {
+	case "skip":
+		p.dirs.skip[key] = true
+
+	case "name":
+		if !pyIdent.MatchString(arg) || pyKeywords[arg] {
+			return fmt.Errorf("invalid python name %q", arg)
+		}
+		p.setAlias(key, arg)
+
+	case "nogil":
+		p.dirs.nogil[key] = true
+
+	case "ctor":
+		fn, ok := lookupDecl(p.pkg, key).(*types.Func)
+		if !ok {
+			break
+		}
+		res := fn.Type().(*types.Signature).Results()
+		var named *types.Named
+		if res.Len() > 0 {
+			named, _ = res.At(0).Type().(*types.Named)
+		}
+		if named == nil || named.Obj().Pkg() != p.pkg {
+			return fmt.Errorf("not a constructor of a struct type")
+		}
+		if _, ok := named.Underlying().(*types.Struct); !ok {
+			return fmt.Errorf("not a constructor of a struct type")
+		}
+		tname := named.Obj().Name()
+		p.dirs.ctors[tname] = append(p.dirs.ctors[tname], key)
+
+	case "property":
+		fn, ok := lookupDecl(p.pkg, key).(*types.Func)
+		if !ok {
+			break
+		}
+		sig := fn.Type().(*types.Signature)
+		recv := sig.Recv().Type()
+		if ptr, ok := recv.(*types.Pointer); ok {
+			recv = ptr.Elem()
+		}
+		if _, ok := recv.Underlying().(*types.Struct); !ok {
+			return fmt.Errorf("not a method of a struct type")
+		}
+		res := sig.Results()
+		if sig.Params().Len() != 0 || res.Len() == 0 || isErrorType(res.At(0).Type()) {
+			return fmt.Errorf("a property takes no argument and returns a value")
+		}
+		p.dirs.props[key] = true
+
+	case "instantiate":
+		// the instantiations are collected by addInstances.
+		obj := p.pkg.Scope().Lookup(key)
+		if obj == nil || !isGeneric(obj.Type()) {
+			return fmt.Errorf("not a generic type")
+		}
+	}
+	return nil
+}
+
+// excluded returns why the declaration, field or method key is not bound,
+// according to the //gopy:skip directives and to the configuration, or ""
+// if it is bound.
+func (p *Package) excluded(key string) string {
+	switch {
+	case p.dirs.skip[key]:
+		return "excluded by a //gopy:skip directive"
+	case strings.Contains(key, "."):
+		if contains(p.cfg.Exclude, key) {
+			return "excluded by the configuration"
+		}
+	case !p.cfg.included(key):
+		return "excluded by the configuration"
+	}
+	return ""
+}
+
+// releaseGIL reports whether the GIL is released while the Go code of the
+// function or method key runs.
+func (p *Package) releaseGIL(key string) bool {
+	if _, ok := p.cfg.GIL[key]; !ok && p.dirs.nogil[key] {
+		return true
+	}
+	return p.cfg.releaseGIL(key)
+}
+
+// ctors returns the names of the functions exposed as the constructors of
+// the struct type name, and whether they are restricted to those (rather
+// than all the functions returning the type).
+func (p *Package) ctors(name string) ([]string, bool) {
+	if ctors, ok := p.cfg.Ctors[name]; ok {
+		return ctors, true
+	}
+	ctors, ok := p.dirs.ctors[name]
+	return ctors, ok
+}
@@ -0,0 +1,795 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	capiPreamble = `// Package main is an autogenerated C API for package %[1]s.
+// gopy gen -lang=c %[2]s
+//
+// File is generated by gopy gen. Do not edit.
+package main
+
+/*
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+// %[1]s_handle is a handle to a Go value of package %[1]s.
+// The zero handle stands for nil.
+// Handles returned by the functions of this API must be released with
+// %[1]s_free.
+typedef int64_t %[1]s_handle;
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// %[1]s_free releases the handle h.
+// The Go value it refers to is garbage collected once it is not referenced
+// anymore.
+extern void %[1]s_free(%[1]s_handle h);
+
+// %[1]s_free_string releases a string returned by the functions of this API,
+// including error messages.
+extern void %[1]s_free_string(char* s);
+%[3]s
+#ifdef __cplusplus
+}
+#endif
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	%[4]s
+)
+
+// handles stores the Go values passed to C.
+var handles struct {
+	sync.Mutex
+	next int64
+	m    map[int64]interface{}
+}
+
+// cgopy_new_handle returns a new handle to the non-nil pointer p.
+func cgopy_new_handle(p interface{}) C.%[1]s_handle {
+	handles.Lock()
+	defer handles.Unlock()
+	if handles.m == nil {
+		handles.m = make(map[int64]interface{})
+	}
+	handles.next++
+	handles.m[handles.next] = p
+	return C.%[1]s_handle(handles.next)
+}
+
+// cgopy_get_handle returns the pointer the non-zero handle h refers to.
+func cgopy_get_handle(h C.%[1]s_handle) interface{} {
+	handles.Lock()
+	p, ok := handles.m[int64(h)]
+	handles.Unlock()
+	if !ok {
+		panic("gopy: invalid handle")
+	}
+	return p
+}
+
+// cgopy_string returns the Go-syntax representation of v.
+func cgopy_string(v interface{}) *C.char {
+	return C.CString(fmt.Sprintf("%%#v", v))
+}
+
+// cgopy_set_error stores the message of err in *errp, if err and errp are not
+// nil.
+func cgopy_set_error(errp **C.char, err error) {
+	if err != nil && errp != nil {
+		*errp = C.CString(err.Error())
+	}
+}
+
+//export %[1]s_free
+func %[1]s_free(h C.%[1]s_handle) {
+	handles.Lock()
+	delete(handles.m, int64(h))
+	handles.Unlock()
+}
+
+//export %[1]s_free_string
+func %[1]s_free_string(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+`
+)
+
+// GenC generates a cgo package exposing a Go package through a plain C API.
+// The documented declarations of the API are written in the preamble of the
+// package, which cgo copies to the C header it exports.
+func GenC(w io.Writer, fset *token.FileSet, pkg *Package) error {
+	g := newCGen(fset, pkg)
+	g.gen()
+
+	pkgimport := fmt.Sprintf("%q", pkg.ImportPath())
+	if !g.used {
+		pkgimport = fmt.Sprintf("_ %q", pkg.ImportPath())
+	}
+	_, err := fmt.Fprintf(w, capiPreamble, pkg.Name(), pkg.ImportPath(), g.defs.String(), pkgimport)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, g.buf)
+	return err
+}
+
+// cGen generates the Go half of the C API of a package.
+type cGen struct {
+	*printer
+
+	defs  *bytes.Buffer // C definitions of the preamble
+	fset  *token.FileSet
+	pkg   *Package
+	used  bool                 // whether the generated code refers to the package
+	funcs map[string]*capiFunc // functions of the C API, by C name
+	order []string             // C names of the functions, in declaration order
+	seqs  []*cseq              // slices and arrays passed by handle
+}
+
+// cseq describes a slice or array type passed through the C API by handle.
+type cseq struct {
+	name  string     // name of the type in the C API
+	typ   types.Type // slice or array type
+	elem  types.Type // type of the elements
+	slice bool       // whether typ is a slice
+}
+
+func newCGen(fset *token.FileSet, pkg *Package) *cGen {
+	return &cGen{
+		printer: &printer{buf: new(bytes.Buffer), indentEach: []byte("\t")},
+		defs:    new(bytes.Buffer),
+		fset:    fset,
+		pkg:     pkg,
+		funcs:   make(map[string]*capiFunc),
+	}
+}
+
+// capiFunc describes a function of the C API.
+type capiFunc struct {
+	name   string
+	params []cparam
+	ret    types.Type // nil for no result
+}
+
+// decl returns the C declaration of the function.
+func (f *capiFunc) decl(g *cGen) string {
+	var params []string
+	for _, p := range f.params {
+		params = append(params, cdecl(g.paramType(p))+" "+p.name)
+	}
+	if len(params) == 0 {
+		params = []string{"void"}
+	}
+	ret := "void"
+	if f.ret != nil {
+		ctyp, _ := g.ctype(f.ret)
+		ret = cdecl(ctyp)
+	}
+	return fmt.Sprintf("%s %s(%s)", ret, f.name, strings.Join(params, ", "))
+}
+
+func (g *cGen) gen() {
+	for _, c := range g.pkg.consts {
+		g.genConst(c)
+	}
+
+	for _, v := range g.pkg.vars {
+		g.genVar(v)
+	}
+
+	for _, s := range g.pkg.structs {
+		g.genStruct(s)
+	}
+
+	for _, s := range g.pkg.structs {
+		for _, ctor := range s.ctors {
+			g.genFunc(ctor, "")
+		}
+	}
+
+	for _, f := range g.pkg.funcs {
+		g.genFunc(f, "")
+	}
+
+	// the slices and arrays exposed by the CPython extension are part of the
+	// API, as well as those the declarations above refer to.
+	for _, n := range g.pkg.syms.names() {
+		sym := g.pkg.syms.sym(n)
+		if sym.isType() && (sym.isSlice() || sym.isArray()) && sym.GoType() != nil {
+			g.seqType(sym.GoType())
+		}
+	}
+	for i := 0; i < len(g.seqs); i++ {
+		g.genSeq(g.seqs[i])
+	}
+
+	g.Printf("\n// buildmode=c-shared needs a 'main'\nfunc main() {}\n")
+}
+
+// cname returns the C name of the declaration named by the parts.
+func (g *cGen) cname(parts ...string) string {
+	return g.pkg.Name() + "_" + strings.Join(parts, "_")
+}
+
+// gotype returns the Go type expression of typ in the generated code.
+func (g *cGen) gotype(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg == g.pkg.pkg {
+			g.used = true
+		}
+		return pkg.Name()
+	})
+}
+
+// handleType returns the name of the bound struct, slice or array type typ
+// refers to, either directly or through a pointer, or "" if there is none.
+// The values of these types are passed by handle.
+func (g *cGen) handleType(typ types.Type) (name string, ptr bool) {
+	if p, ok := typ.(*types.Pointer); ok {
+		typ = p.Elem()
+		ptr = true
+	}
+	for _, s := range g.pkg.structs {
+		if types.Identical(s.GoType(), typ) {
+			return s.GoName(), ptr
+		}
+	}
+	if s := g.seqType(typ); s != nil {
+		return s.name, ptr
+	}
+	return "", false
+}
+
+// seqType returns the description of the slice or array type typ, or nil if
+// typ is not a slice or an array or if its elements can not be passed through
+// the C API.
+func (g *cGen) seqType(typ types.Type) *cseq {
+	for _, s := range g.seqs {
+		if types.Identical(s.typ, typ) {
+			return s
+		}
+	}
+	if named, ok := typ.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != g.pkg.pkg || !obj.Exported() {
+			return nil
+		}
+	}
+	s := &cseq{name: typeArgName(typ), typ: typ}
+	switch t := typ.Underlying().(type) {
+	case *types.Slice:
+		s.elem = t.Elem()
+		s.slice = true
+	case *types.Array:
+		s.elem = t.Elem()
+	default:
+		return nil
+	}
+	// s is recorded first for the recursive types, like type L []L.
+	g.seqs = append(g.seqs, s)
+	if _, err := g.ctype(s.elem); err != nil {
+		g.seqs = g.seqs[:len(g.seqs)-1]
+		return nil
+	}
+	return s
+}
+
+// ctype returns the Go spelling of the C type used to pass values of typ
+// through the C API, or an error if typ can not be passed.
+func (g *cGen) ctype(typ types.Type) (string, error) {
+	if name, _ := g.handleType(typ); name != "" {
+		return "C." + g.cname("handle"), nil
+	}
+	if basic, ok := typ.Underlying().(*types.Basic); ok {
+		switch basic.Kind() {
+		case types.Bool:
+			return "C.bool", nil
+		case types.Int, types.Int64:
+			return "C.int64_t", nil
+		case types.Int8:
+			return "C.int8_t", nil
+		case types.Int16:
+			return "C.int16_t", nil
+		case types.Int32:
+			return "C.int32_t", nil
+		case types.Uint, types.Uint64:
+			return "C.uint64_t", nil
+		case types.Uint8:
+			return "C.uint8_t", nil
+		case types.Uint16:
+			return "C.uint16_t", nil
+		case types.Uint32:
+			return "C.uint32_t", nil
+		case types.Uintptr:
+			return "C.uintptr_t", nil
+		case types.Float32:
+			return "C.float", nil
+		case types.Float64:
+			return "C.double", nil
+		case types.String:
+			return "*C.char", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported type %s", g.qualified(typ))
+}
+
+// qualified returns the name of typ, qualified by package names.
+func (g *cGen) qualified(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string { return pkg.Name() })
+}
+
+// togo returns the expression converting the C value x to typ.
+func (g *cGen) togo(typ types.Type, x string) string {
+	if name, ptr := g.handleType(typ); name != "" {
+		deref := fmt.Sprintf("cgopy_deref_%s(%s)", name, x)
+		if ptr {
+			return deref
+		}
+		return "*" + deref
+	}
+	basic := typ.Underlying().(*types.Basic)
+	if basic.Kind() == types.String {
+		x = "C.GoString(" + x + ")"
+		if typ == basic {
+			return x
+		}
+	}
+	return g.gotype(typ) + "(" + x + ")"
+}
+
+// toc returns the expression converting the Go value x of type typ to C.
+func (g *cGen) toc(typ types.Type, x string) string {
+	if name, ptr := g.handleType(typ); name != "" {
+		if ptr {
+			return "cgopy_new_" + name + "(" + x + ")"
+		}
+		return "cgopy_box_" + name + "(" + x + ")"
+	}
+	ctyp, _ := g.ctype(typ)
+	basic := typ.Underlying().(*types.Basic)
+	if basic.Kind() == types.String {
+		return "C.CString(string(" + x + "))"
+	}
+	return ctyp + "(" + x + ")"
+}
+
+// ref returns the expression converting the variable or field x of type typ
+// to C.
+// As with the CPython extension, the structs, slices and arrays are passed by
+// reference: changing them from C changes x.
+func (g *cGen) ref(typ types.Type, x string) string {
+	if name, ptr := g.handleType(typ); name != "" && !ptr {
+		return "cgopy_new_" + name + "(&" + x + ")"
+	}
+	return g.toc(typ, x)
+}
+
+// skip records in the generated code that the declaration name is not part
+// of the C API.
+func (g *cGen) skip(name string, err error) {
+	g.Printf("\n// %s is not part of the C API: %v.\n", name, err)
+}
+
+// cparam is a parameter of a function of the C API.
+type cparam struct {
+	name string
+	typ  types.Type // nil for the error out-parameter
+}
+
+// paramType returns the Go spelling of the C type of the parameter p.
+func (g *cGen) paramType(p cparam) string {
+	if p.typ == nil {
+		return "**C.char"
+	}
+	ctyp, _ := g.ctype(p.typ)
+	return ctyp
+}
+
+// export starts the Go function fct exported to C, and declares it with its
+// documentation doc in the C header.
+// The types of params and ret must be supported by the C API.
+func (g *cGen) export(fct, doc string, params []cparam, ret types.Type) {
+	fmt.Fprintf(g.defs, "\n")
+	for _, line := range strings.Split(strings.TrimSpace(doc), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			fmt.Fprintf(g.defs, "//\n")
+			continue
+		}
+		fmt.Fprintf(g.defs, "// %s\n", line)
+	}
+
+	f := &capiFunc{name: fct, params: params, ret: ret}
+	g.funcs[fct] = f
+	g.order = append(g.order, fct)
+	fmt.Fprintf(g.defs, "extern %s;\n", f.decl(g))
+
+	var goparams []string
+	for _, p := range params {
+		goparams = append(goparams, p.name+" "+g.paramType(p))
+	}
+	goret := ""
+	if ret != nil {
+		ctyp, _ := g.ctype(ret)
+		goret = " " + ctyp
+	}
+	g.Printf("\n//export %s\n", fct)
+	g.Printf("func %s(%s)%s {\n", fct, strings.Join(goparams, ", "), goret)
+	g.Indent()
+}
+
+// cdecl returns the C spelling of the C type typ spelled in Go.
+func cdecl(typ string) string {
+	n := len(typ) - len(strings.TrimLeft(typ, "*"))
+	return strings.TrimPrefix(typ[n:], "C.") + strings.Repeat("*", n)
+}
+
+// goDoc returns doc, separated from the preceding documentation by an empty
+// line if it is not empty.
+func goDoc(doc string) string {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return ""
+	}
+	return "\n\n" + doc
+}
+
+func (g *cGen) genConst(c Const) {
+	val := c.obj.Val()
+	var def string
+	switch val.Kind() {
+	case constant.Bool:
+		def = "0"
+		if constant.BoolVal(val) {
+			def = "1"
+		}
+	case constant.String:
+		def = strconv.Quote(constant.StringVal(val))
+	case constant.Int:
+		if _, ok := constant.Int64Val(val); !ok {
+			if _, ok := constant.Uint64Val(val); !ok {
+				g.skip(c.obj.Name(), fmt.Errorf("constant %s overflows 64 bits", val))
+				return
+			}
+		}
+		def = val.ExactString()
+	case constant.Float:
+		f, _ := constant.Float64Val(val)
+		def = strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(def, ".eEn") {
+			def += ".0"
+		}
+	default:
+		g.skip(c.obj.Name(), fmt.Errorf("unsupported constant %s", val))
+		return
+	}
+	if strings.HasPrefix(def, "-") {
+		def = "(" + def + ")"
+	}
+	fmt.Fprintf(g.defs, "\n")
+	if doc := strings.TrimSpace(c.doc); doc != "" {
+		for _, line := range strings.Split(doc, "\n") {
+			fmt.Fprintf(g.defs, "%s\n", strings.TrimRight("// "+line, " \t"))
+		}
+	}
+	fmt.Fprintf(g.defs, "#define %s %s\n", g.cname(c.obj.Name()), def)
+}
+
+func (g *cGen) genVar(v Var) {
+	typ := v.GoType()
+	_, err := g.ctype(typ)
+	if err != nil {
+		g.skip(v.Name(), err)
+		return
+	}
+	g.used = true
+	pkg := g.pkg.Name()
+
+	get := g.cname("get", v.Name())
+	g.export(get,
+		fmt.Sprintf("%s returns the value of the variable %s.%s", get, v.Name(), goDoc(v.doc)),
+		nil, typ,
+	)
+	g.Printf("return %s\n", g.ref(typ, pkg+"."+v.Name()))
+	g.Outdent()
+	g.Printf("}\n")
+
+	set := g.cname("set", v.Name())
+	g.export(set,
+		fmt.Sprintf("%s sets the value of the variable %s.", set, v.Name()),
+		[]cparam{{"v", typ}}, nil,
+	)
+	g.Printf("%s.%s = %s\n", pkg, v.Name(), g.togo(typ, "v"))
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+func (g *cGen) genStruct(s Struct) {
+	name := s.GoName()
+	gotyp := g.gotype(s.GoType())
+	self := types.NewPointer(s.GoType())
+	g.genHandles(name, gotyp)
+
+	fct := g.cname(name, "new")
+	g.export(fct,
+		fmt.Sprintf("%s returns a handle to a new zero value of type %s.%s", fct, name, goDoc(s.Doc())),
+		nil, self,
+	)
+	g.Printf("return cgopy_new_handle(new(%s))\n", gotyp)
+	g.Outdent()
+	g.Printf("}\n")
+
+	fct = g.cname(name, "string")
+	if (s.prots & ProtoStringer) != 0 {
+		g.export(fct,
+			fmt.Sprintf("%s returns the string representation of the %s self, as\nreturned by its String method.", fct, name),
+			[]cparam{{"self", self}}, types.Typ[types.String],
+		)
+		g.Printf("return C.CString(cgopy_deref_%s(self).String())\n", name)
+	} else {
+		g.export(fct,
+			fmt.Sprintf("%s returns the Go-syntax representation of the %s self.", fct, name),
+			[]cparam{{"self", self}}, types.Typ[types.String],
+		)
+		g.Printf("return cgopy_string(*cgopy_deref_%s(self))\n", name)
+	}
+	g.Outdent()
+	g.Printf("}\n")
+
+	st := s.Struct()
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Exported() {
+			continue
+		}
+		_, err := g.ctype(f.Type())
+		if err != nil {
+			g.skip(name+"."+f.Name(), err)
+			continue
+		}
+
+		get := g.cname(name, "get", f.Name())
+		g.export(get,
+			fmt.Sprintf("%s returns the field %s of the %s self.%s", get, f.Name(), name, goDoc(s.fdocs[f.Name()])),
+			[]cparam{{"self", self}}, f.Type(),
+		)
+		g.Printf("return %s\n", g.ref(f.Type(), "cgopy_deref_"+name+"(self)."+f.Name()))
+		g.Outdent()
+		g.Printf("}\n")
+
+		set := g.cname(name, "set", f.Name())
+		g.export(set,
+			fmt.Sprintf("%s sets the field %s of the %s self.", set, f.Name(), name),
+			[]cparam{{"self", self}, {"v", f.Type()}}, nil,
+		)
+		g.Printf("cgopy_deref_%s(self).%s = %s\n", name, f.Name(), g.togo(f.Type(), "v"))
+		g.Outdent()
+		g.Printf("}\n")
+	}
+
+	for _, m := range s.meths {
+		g.genFunc(m, name)
+	}
+}
+
+// genHandles generates the functions converting the values of the Go type
+// gotyp, named name in the C API, from and to handles.
+func (g *cGen) genHandles(name, gotyp string) {
+	handle := "C." + g.cname("handle")
+
+	g.Printf("\n// cgopy_new_%[1]s returns a new handle to p, or the zero handle if p is nil.\n", name)
+	g.Printf("func cgopy_new_%s(p *%s) %s {\n", name, gotyp, handle)
+	g.Indent()
+	g.Printf("if p == nil {\n\treturn 0\n}\n")
+	g.Printf("return cgopy_new_handle(p)\n")
+	g.Outdent()
+	g.Printf("}\n")
+
+	g.Printf("\n// cgopy_box_%[1]s returns a new handle to a copy of v.\n", name)
+	g.Printf("func cgopy_box_%s(v %s) %s {\n", name, gotyp, handle)
+	g.Indent()
+	g.Printf("return cgopy_new_handle(&v)\n")
+	g.Outdent()
+	g.Printf("}\n")
+
+	g.Printf("\n// cgopy_deref_%[1]s returns the %[1]s the handle h refers to, or nil for\n// the zero handle.\n", name)
+	g.Printf("func cgopy_deref_%s(h %s) *%s {\n", name, handle, gotyp)
+	g.Indent()
+	g.Printf("if h == 0 {\n\treturn nil\n}\n")
+	g.Printf("return cgopy_get_handle(h).(*%s)\n", gotyp)
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// genSeq generates the functions of the C API handling the values of the
+// slice or array type s.
+func (g *cGen) genSeq(s *cseq) {
+	name := s.name
+	gotyp := g.gotype(s.typ)
+	self := types.NewPointer(s.typ)
+	index := types.Typ[types.Int]
+	g.genHandles(name, gotyp)
+
+	fct := g.cname(name, "new")
+	what := "a new zero"
+	if s.slice {
+		what = "a new empty"
+	}
+	g.export(fct,
+		fmt.Sprintf("%s returns a handle to %s value of type %s.", fct, what, g.qualified(s.typ)),
+		nil, self,
+	)
+	g.Printf("return cgopy_new_handle(new(%s))\n", gotyp)
+	g.Outdent()
+	g.Printf("}\n")
+
+	fct = g.cname(name, "string")
+	g.export(fct,
+		fmt.Sprintf("%s returns the Go-syntax representation of the %s self.", fct, name),
+		[]cparam{{"self", self}}, types.Typ[types.String],
+	)
+	g.Printf("return cgopy_string(*cgopy_deref_%s(self))\n", name)
+	g.Outdent()
+	g.Printf("}\n")
+
+	fct = g.cname(name, "len")
+	g.export(fct,
+		fmt.Sprintf("%s returns the number of elements of the %s self.", fct, name),
+		[]cparam{{"self", self}}, index,
+	)
+	g.Printf("return %s\n", g.toc(index, "len(*cgopy_deref_"+name+"(self))"))
+	g.Outdent()
+	g.Printf("}\n")
+
+	elem := fmt.Sprintf("(*cgopy_deref_%s(self))[%s]", name, g.togo(index, "i"))
+	get := g.cname(name, "get")
+	g.export(get,
+		fmt.Sprintf("%s returns the element i of the %s self.\ni must be less than %s(self).", get, name, fct),
+		[]cparam{{"self", self}, {"i", index}}, s.elem,
+	)
+	g.Printf("return %s\n", g.toc(s.elem, elem))
+	g.Outdent()
+	g.Printf("}\n")
+
+	set := g.cname(name, "set")
+	g.export(set,
+		fmt.Sprintf("%s sets the element i of the %s self.\ni must be less than %s(self).", set, name, fct),
+		[]cparam{{"self", self}, {"i", index}, {"v", s.elem}}, nil,
+	)
+	g.Printf("%s = %s\n", elem, g.togo(s.elem, "v"))
+	g.Outdent()
+	g.Printf("}\n")
+
+	if !s.slice {
+		return
+	}
+	fct = g.cname(name, "append")
+	g.export(fct,
+		fmt.Sprintf("%s appends v to the %s self.", fct, name),
+		[]cparam{{"self", self}, {"v", s.elem}}, nil,
+	)
+	g.Printf("s := cgopy_deref_%s(self)\n", name)
+	g.Printf("*s = append(*s, %s)\n", g.togo(s.elem, "v"))
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// genFunc generates the C entry point of the function f or, if recv is not
+// empty, of the method f of the struct named recv.
+func (g *cGen) genFunc(f Func, recv string) {
+	sig := f.GoType().(*types.Signature)
+	name := f.GoName()
+	if recv != "" {
+		name = recv + "." + name
+	}
+
+	var (
+		params []cparam
+		args   []string
+		names  = make(map[string]bool)
+	)
+	if recv != "" {
+		params = append(params, cparam{"self", sig.Recv().Type()})
+		names["self"] = true
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		p := sig.Params().At(i)
+		_, err := g.ctype(p.Type())
+		if err != nil {
+			g.skip(name, err)
+			return
+		}
+		pname := p.Name()
+		if pname == "" || pname == "_" || names[pname] {
+			pname = fmt.Sprintf("arg%d", i)
+		}
+		names[pname] = true
+		params = append(params, cparam{pname, p.Type()})
+		args = append(args, g.togo(p.Type(), pname))
+	}
+
+	ret := f.ret
+	if ret != nil {
+		_, err := g.ctype(ret)
+		if err != nil {
+			g.skip(name, err)
+			return
+		}
+	}
+	errp := ""
+	if f.err {
+		errp = "err"
+		for names[errp] {
+			errp += "_"
+		}
+		params = append(params, cparam{errp, nil})
+	}
+
+	fct := g.cname(f.GoName())
+	call := g.pkg.Name() + "." + f.GoName()
+	if recv != "" {
+		fct = g.cname(recv, f.GoName())
+		call = fmt.Sprintf("cgopy_deref_%s(self).%s", recv, f.GoName())
+	}
+	g.used = true
+
+	doc := fmt.Sprintf("%s calls %s.%s", fct, name, goDoc(funcDoc(f)))
+	if errp != "" {
+		doc += fmt.Sprintf(
+			"\n\nOn failure, *%s is set to the error message, to be released with\n%s.",
+			errp, g.cname("free_string"),
+		)
+	}
+	g.export(fct, doc, params, ret)
+	call += "(" + strings.Join(args, ", ") + ")"
+	switch {
+	case ret != nil && errp != "":
+		g.Printf("cgopy_res, cgopy_err := %s\n", call)
+		g.Printf("cgopy_set_error(%s, cgopy_err)\n", errp)
+		g.Printf("return %s\n", g.toc(ret, "cgopy_res"))
+	case ret != nil:
+		g.Printf("return %s\n", g.toc(ret, call))
+	case errp != "":
+		g.Printf("cgopy_set_error(%s, %s)\n", errp, call)
+	default:
+		g.Printf("%s\n", call)
+	}
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// funcDoc returns the doc comment of f, without the python signature
+// prepended by Package.getDoc.
+func funcDoc(f Func) string {
+	doc := f.Doc()
+	if !strings.HasPrefix(doc, f.GoName()+"(") {
+		return doc
+	}
+	if i := strings.Index(doc, "\n"); i >= 0 {
+		return doc[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,729 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	cffiPreamble = `# Package %[1]s is an autogenerated cffi binding of the Go package %[2]s.
+# gopy gen -lang=cffi %[2]s
+#
+# File is generated by gopy gen. Do not edit.
+%[3]s
+import os
+import sys
+import types
+
+import cffi
+
+ffi = cffi.FFI()
+ffi.cdef("""
+typedef int64_t %[1]s_handle;
+void %[1]s_free(%[1]s_handle h);
+void %[1]s_free_string(char* s);
+%[4]s""")
+
+_lib = ffi.dlopen(os.path.join(os.path.dirname(os.path.abspath(__file__)), "lib%[1]s.so"))
+
+
+def _gostr(p):
+    # returns the python string of the C string p, and releases p.
+    s = ffi.string(p)
+    _lib.%[1]s_free_string(p)
+    if sys.version_info[0] >= 3:
+        return s.decode("utf-8")
+    return s
+
+
+def _cstr(s):
+    # returns the C string of the python string s.
+    if not isinstance(s, bytes):
+        s = s.encode("utf-8")
+    return s
+
+
+def _check(err):
+    # raises the error stored in the error out-parameter err, if any.
+    if err[0] != ffi.NULL:
+        raise RuntimeError(_gostr(err[0]))
+
+
+def _handle(obj, cls):
+    # returns the handle of obj, an instance of cls or None.
+    if obj is None:
+        return 0
+    if not isinstance(obj, cls):
+        raise TypeError("expected %%s, got %%s" %% (cls.__name__, type(obj).__name__))
+    return obj._handle
+
+
+def _value(obj, cls):
+    # returns the handle of obj, an instance of cls.
+    if obj is None:
+        raise TypeError("expected %%s, got None" %% (cls.__name__,))
+    return _handle(obj, cls)
+
+
+def _wrap(cls, h):
+    # returns an instance of cls owning the handle h, or None for the zero
+    # handle.
+    if h == 0:
+        return None
+    obj = cls.__new__(cls)
+    obj._handle = h
+    return obj
+
+
+def _from_native(cls, v):
+    # returns v, converted to an instance of cls if cls is the class of a
+    # struct and v a dict, or the class of a slice or an array and v a
+    # sequence.
+    if v is None or isinstance(v, cls):
+        return v
+    if issubclass(cls, _Seq):
+        return cls(v)
+    if not isinstance(v, dict):
+        raise TypeError("expected a dict or %%s, got %%s" %% (cls.__name__, type(v).__name__))
+    return _from_dict(cls, v)
+
+
+def _native(v):
+    # returns the native python value of v: a dict for a struct, a list for a
+    # slice or an array.
+    if isinstance(v, _Seq):
+        return [_native(x) for x in v]
+    if isinstance(v, _Object):
+        return v.to_dict()
+    return v
+
+
+def _from_dict(cls, d):
+    # returns a new instance of cls, with the fields set from the dict d.
+    if not isinstance(d, dict):
+        raise TypeError("expected a dict, got %%s" %% (type(d).__name__,))
+    fields = dict(cls._dict)
+    obj = cls()
+    for k, v in d.items():
+        if k not in fields:
+            raise TypeError("unknown field '%%s'" %% (k,))
+        try:
+            getattr(cls, "_set_" + fields[k])(obj, v)
+        except TypeError as err:
+            msg = str(err)
+            if msg.startswith("field '"):
+                raise TypeError("field '%%s.%%s" %% (k, msg[len("field '"):]))
+            raise TypeError("field '%%s': %%s" %% (k, msg))
+    return obj
+
+
+def _init(obj, fields, args, kwargs):
+    # sets the fields of obj from the arguments of its constructor.
+    name = type(obj).__name__
+    if len(args) + len(kwargs) > len(fields):
+        raise TypeError("%%s.__init__ takes at most %%d argument(s)" %% (name, len(fields)))
+    setters = dict(fields)
+    for (_, set), v in zip(fields, args):
+        set(obj, v)
+    for k, v in kwargs.items():
+        if k not in setters:
+            raise TypeError("'%%s' is an invalid keyword argument for this function" %% (k,))
+        setters[k](obj, v)
+
+
+def _readonly(name):
+    # returns the setter of the read-only attribute name.
+    def set(obj, v):
+        raise AttributeError("attribute '%%s' of '%%s.%%s' objects is not writable" %% (
+            name, type(obj).__module__, type(obj).__name__))
+    return set
+
+
+class _Object(object):
+    # _Object is a Go value, referred to by a handle of the C API.
+    __slots__ = ("_handle",)
+
+    def __del__(self):
+        if getattr(self, "_handle", 0):
+            _lib.%[1]s_free(self._handle)
+            self._handle = 0
+
+
+class _Seq(_Object):
+    # _Seq is a Go slice or array. Its subclasses implement __len__, _get and
+    # _set.
+    __slots__ = ()
+
+    def _index(self, i):
+        # returns the index of the element i, counted from the end if negative.
+        n = len(self)
+        if i < 0:
+            i += n
+        if not 0 <= i < n:
+            raise IndexError("index out of range")
+        return i
+
+    def __getitem__(self, i):
+        return self._get(self._index(i))
+
+    def __setitem__(self, i, v):
+        self._set(self._index(i), v)
+
+    def __iter__(self):
+        for i in range(len(self)):
+            yield self._get(i)
+
+    def _fill(self, seq):
+        # sets the first elements of the array to those of seq.
+        seq = list(seq)
+        if len(seq) > len(self):
+            raise ValueError("%%d elements for an array of length %%d" %% (len(seq), len(self)))
+        for i, v in enumerate(seq):
+            self._set(i, v)
+`
+)
+
+// GenCFFI generates a pure-python module exposing a Go package through the C
+// API generated by GenC, using cffi in ABI mode.
+// The module loads the C API from the lib<package>.so shared library, next
+// to the module.
+func GenCFFI(w io.Writer, fset *token.FileSet, pkg *Package) error {
+	c := newCGen(fset, pkg)
+	c.gen()
+
+	g := &cffiGen{
+		printer: &printer{buf: new(bytes.Buffer), indentEach: []byte("    ")},
+		c:       c,
+		pkg:     pkg,
+	}
+	g.gen()
+
+	cdefs := new(bytes.Buffer)
+	for _, name := range c.order {
+		fmt.Fprintf(cdefs, "%s;\n", c.funcs[name].decl(c))
+	}
+
+	doc := ""
+	if pkg.doc.Doc != "" {
+		doc = "\n" + pyDocString(pkg.doc.Doc) + "\n"
+	}
+	_, err := fmt.Fprintf(w, cffiPreamble, pkg.Name(), pkg.ImportPath(), doc, cdefs.String())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, g.buf)
+	return err
+}
+
+// cffiGen generates a python module calling the C API of a package with cffi.
+type cffiGen struct {
+	*printer
+
+	c   *cGen // the C API of the package
+	pkg *Package
+}
+
+func (g *cffiGen) gen() {
+	for _, c := range g.pkg.consts {
+		g.genConst(c)
+	}
+
+	for _, s := range g.pkg.structs {
+		g.genStruct(s)
+	}
+
+	for _, s := range g.c.seqs {
+		g.genSeq(s)
+	}
+
+	for _, s := range g.pkg.structs {
+		for _, ctor := range s.ctors {
+			g.genFunc(ctor, "")
+		}
+	}
+
+	for _, f := range g.pkg.funcs {
+		g.genFunc(f, "")
+	}
+
+	var vars []*Var
+	for i := range g.pkg.vars {
+		v := &g.pkg.vars[i]
+		if g.genVar(v) {
+			vars = append(vars, v)
+		}
+	}
+	if len(vars) == 0 {
+		return
+	}
+
+	// expose the variables as attributes of the module, as the
+	// CPython extension does.
+	g.nl(2)
+	g.Printf("class _Module(types.ModuleType):\n")
+	g.Indent()
+	g.Printf("# _Module exposes the variables of the package as module attributes.\n")
+	for _, v := range vars {
+		g.Printf("%s = property(lambda self: %s(), lambda self, v: %s(v))\n",
+			v.pyName(g.pkg.naming),
+			g.pkg.naming.accessor("get", v.Name(), v.alias),
+			g.pkg.naming.accessor("set", v.Name(), v.alias),
+		)
+	}
+	g.Outdent()
+	g.nl(2)
+	g.Printf("_module = _Module(__name__, __doc__)\n")
+	g.Printf("_module.__dict__.update(globals())\n")
+	g.Printf("_module._orig = sys.modules[__name__]\n")
+	g.Printf("sys.modules[__name__] = _module\n")
+}
+
+// class returns the name of the python class of the bound struct, slice or
+// array typ refers to, or "" if there is none.
+func (g *cffiGen) class(typ types.Type) (name string, ptr bool) {
+	return g.c.handleType(typ)
+}
+
+// genConv prints the statement converting the python value x of type typ
+// from a native python value, before it is passed to the C API.
+// The conversion is a statement, so that the converted value lives until the
+// C API returns.
+func (g *cffiGen) genConv(typ types.Type, x string) {
+	if name, _ := g.class(typ); name != "" {
+		g.Printf("%s = _from_native(%s, %s)\n", x, name, x)
+	}
+}
+
+// topy returns the python expression converting the result x of the C API,
+// of type typ, to python.
+func (g *cffiGen) topy(typ types.Type, x string) string {
+	if name, _ := g.class(typ); name != "" {
+		return "_wrap(" + name + ", " + x + ")"
+	}
+	if basic, ok := typ.Underlying().(*types.Basic); ok && basic.Kind() == types.String {
+		return "_gostr(" + x + ")"
+	}
+	return x
+}
+
+// toc returns the python expression converting the python value x of type
+// typ to an argument of the C API.
+func (g *cffiGen) toc(typ types.Type, x string) string {
+	if name, ptr := g.class(typ); name != "" {
+		if ptr {
+			return "_handle(" + x + ", " + name + ")"
+		}
+		return "_value(" + x + ", " + name + ")"
+	}
+	if basic, ok := typ.Underlying().(*types.Basic); ok && basic.Kind() == types.String {
+		return "_cstr(" + x + ")"
+	}
+	return x
+}
+
+// skip records in the generated module that the declaration name is not
+// exposed, after n empty lines.
+func (g *cffiGen) skip(name string, n int) {
+	g.nl(n)
+	g.Printf("# %s is not exposed: it is not part of the C API.\n", name)
+}
+
+// nl prints n empty lines.
+func (g *cffiGen) nl(n int) {
+	g.buf.WriteString(strings.Repeat("\n", n))
+}
+
+// genDoc prints the docstring doc.
+func (g *cffiGen) genDoc(doc string) {
+	if strings.TrimSpace(doc) == "" {
+		return
+	}
+	for _, line := range strings.Split(pyDocString(doc), "\n") {
+		if line == "" {
+			g.nl(1)
+			continue
+		}
+		g.Printf("%s\n", line)
+	}
+}
+
+func (g *cffiGen) genConst(c Const) {
+	val := c.obj.Val()
+	var lit string
+	switch val.Kind() {
+	case constant.Bool:
+		lit = "False"
+		if constant.BoolVal(val) {
+			lit = "True"
+		}
+	case constant.String:
+		lit = pyQuote(constant.StringVal(val))
+	case constant.Int:
+		lit = val.ExactString()
+	case constant.Float:
+		f, _ := constant.Float64Val(val)
+		lit = strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(lit, ".eEn") {
+			lit += ".0"
+		}
+	default:
+		g.nl(1)
+		g.Printf("# %s is not exposed: unsupported constant %s.\n", c.GoName(), val)
+		return
+	}
+
+	name := c.pyName(g.pkg.naming)
+	g.nl(1)
+	g.Printf("%s = %s\n", name, lit)
+	g.nl(2)
+	g.Printf("def %s():\n", g.pkg.naming.accessor("get", c.GoName(), c.alias))
+	g.Indent()
+	g.genDoc(c.Doc())
+	g.Printf("return %s\n", name)
+	g.Outdent()
+}
+
+// genVar generates the accessors of the variable v, and reports whether v is
+// exposed.
+func (g *cffiGen) genVar(v *Var) bool {
+	get := g.c.cname("get", v.Name())
+	set := g.c.cname("set", v.Name())
+	if g.c.funcs[get] == nil || g.c.funcs[set] == nil {
+		g.skip(v.Name(), 2)
+		return false
+	}
+	typ := v.GoType()
+
+	g.nl(2)
+	g.Printf("def %s():\n", g.pkg.naming.accessor("get", v.Name(), v.alias))
+	g.Indent()
+	g.genDoc(v.doc)
+	g.Printf("return %s\n", g.topy(typ, "_lib."+get+"()"))
+	g.Outdent()
+
+	g.nl(2)
+	g.Printf("def %s(v):\n", g.pkg.naming.accessor("set", v.Name(), v.alias))
+	g.Indent()
+	g.genDoc(v.doc)
+	g.genConv(typ, "v")
+	g.Printf("_lib.%s(%s)\n", set, g.toc(typ, "v"))
+	g.Outdent()
+	return true
+}
+
+func (g *cffiGen) genStruct(s Struct) {
+	name := s.GoName()
+
+	g.nl(2)
+	g.Printf("class %s(_Object):\n", name)
+	g.Indent()
+	g.genDoc(s.Doc())
+	g.Printf("__slots__ = ()\n")
+
+	st := s.Struct()
+	var (
+		fields []string // python names and setters of the fields, for __init__
+		dict   []string // dict keys and Go names of the fields, for to_dict and from_dict
+	)
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		pf := g.pkg.pyField(st, i, g.pkg.naming)
+		get := g.c.cname(name, "get", f.Name())
+		set := g.c.cname(name, "set", f.Name())
+		if pf.hidden || g.c.funcs[get] == nil {
+			continue
+		}
+
+		g.nl(1)
+		g.Printf("def _get_%s(self):\n", f.Name())
+		g.Indent()
+		g.Printf("return %s\n", g.topy(f.Type(), "_lib."+get+"(self._handle)"))
+		g.Outdent()
+		g.nl(1)
+		g.Printf("def _set_%s(self, v):\n", f.Name())
+		g.Indent()
+		g.genConv(f.Type(), "v")
+		g.Printf("_lib.%s(self._handle, %s)\n", set, g.toc(f.Type(), "v"))
+		g.Outdent()
+
+		setter := "_set_" + f.Name()
+		if pf.readonly {
+			setter = "_readonly(" + pyQuote(pf.name) + ")"
+		}
+		g.nl(1)
+		g.Printf("%s = property(_get_%s, %s, doc=%s)\n",
+			pf.name, f.Name(), setter, pyQuote(s.FieldDoc(i)),
+		)
+
+		fields = append(fields, fmt.Sprintf("(%s, _set_%s)", pyQuote(pf.name), f.Name()))
+		if key, ok := g.pkg.dictKey(st, i, g.pkg.naming); ok {
+			dict = append(dict, fmt.Sprintf("(%s, %s)", pyQuote(key), pyQuote(f.Name())))
+		}
+	}
+
+	g.nl(1)
+	g.Printf("_fields = (%s)\n", pyTuple(fields))
+	g.Printf("_dict = (%s)\n", pyTuple(dict))
+
+	g.nl(1)
+	g.Printf("def __init__(self, *args, **kwargs):\n")
+	g.Indent()
+	g.Printf("self._handle = _lib.%s()\n", g.c.cname(name, "new"))
+	g.Printf("_init(self, self._fields, args, kwargs)\n")
+	g.Outdent()
+
+	g.nl(1)
+	g.Printf("def __str__(self):\n")
+	g.Indent()
+	g.Printf("return _gostr(_lib.%s(self._handle))\n", g.c.cname(name, "string"))
+	g.Outdent()
+
+	g.nl(1)
+	g.Printf("def to_dict(self):\n")
+	g.Indent()
+	g.Printf("return dict((k, _native(getattr(self, \"_get_\" + f)())) for k, f in self._dict)\n")
+	g.Outdent()
+
+	g.nl(1)
+	g.Printf("@classmethod\n")
+	g.Printf("def from_dict(cls, d):\n")
+	g.Indent()
+	g.Printf("try:\n")
+	g.Printf("    return _from_dict(cls, d)\n")
+	g.Printf("except TypeError as err:\n")
+	g.Printf("    raise TypeError(%s %% (err,))\n", pyQuote(name+".from_dict: %s"))
+	g.Outdent()
+
+	for _, m := range s.meths {
+		g.genFunc(m, name)
+	}
+	g.Outdent()
+}
+
+// genSeq generates the python class of the slice or array type s.
+func (g *cffiGen) genSeq(s *cseq) {
+	name := s.name
+	fct := func(op string) string { return "_lib." + g.c.cname(name, op) }
+
+	g.nl(2)
+	g.Printf("class %s(_Seq):\n", name)
+	g.Indent()
+	g.Printf("%s\n", pyDocString(fmt.Sprintf("%s is the Go type %s.", name, g.c.qualified(s.typ))))
+	g.Printf("__slots__ = ()\n")
+
+	g.nl(1)
+	g.Printf("def __init__(self, seq=()):\n")
+	g.Indent()
+	g.Printf("self._handle = %s()\n", fct("new"))
+	if s.slice {
+		g.Printf("self += seq\n")
+	} else {
+		g.Printf("self._fill(seq)\n")
+	}
+	g.Outdent()
+
+	g.nl(1)
+	g.Printf("def __len__(self):\n")
+	g.Indent()
+	g.Printf("return %s(self._handle)\n", fct("len"))
+	g.Outdent()
+
+	g.nl(1)
+	g.Printf("def __str__(self):\n")
+	g.Indent()
+	g.Printf("return _gostr(%s(self._handle))\n", fct("string"))
+	g.Outdent()
+
+	g.nl(1)
+	g.Printf("def _get(self, i):\n")
+	g.Indent()
+	g.Printf("return %s\n", g.topy(s.elem, fct("get")+"(self._handle, i)"))
+	g.Outdent()
+
+	g.nl(1)
+	g.Printf("def _set(self, i, v):\n")
+	g.Indent()
+	g.genConv(s.elem, "v")
+	g.Printf("%s(self._handle, i, %s)\n", fct("set"), g.toc(s.elem, "v"))
+	g.Outdent()
+
+	if s.slice {
+		g.nl(1)
+		g.Printf("def __iadd__(self, seq):\n")
+		g.Indent()
+		g.Printf("for v in seq:\n")
+		g.Indent()
+		g.genConv(s.elem, "v")
+		g.Printf("%s(self._handle, %s)\n", fct("append"), g.toc(s.elem, "v"))
+		g.Outdent()
+		g.Printf("return self\n")
+		g.Outdent()
+	}
+	g.Outdent()
+}
+
+// genFunc generates the python function calling the C entry point of the
+// function f or, if recv is not empty, the method f of the class recv.
+func (g *cffiGen) genFunc(f Func, recv string) {
+	fct := g.c.cname(f.GoName())
+	if recv != "" {
+		fct = g.c.cname(recv, f.GoName())
+	}
+	cf := g.c.funcs[fct]
+	if cf == nil {
+		name := f.GoName()
+		n := 2
+		if recv != "" {
+			name = recv + "." + name
+			n = 1
+		}
+		g.skip(name, n)
+		return
+	}
+
+	var (
+		params []string
+		args   []string
+		errp   = false
+	)
+	for i, p := range cf.params {
+		switch {
+		case p.typ == nil:
+			errp = true
+			args = append(args, "_err")
+		case recv != "" && i == 0:
+			params = append(params, "self")
+			args = append(args, "self._handle")
+		default:
+			pname := pyName(p.name)
+			params = append(params, pname)
+			args = append(args, g.toc(p.typ, pname))
+		}
+	}
+
+	if recv != "" {
+		g.nl(1)
+	} else {
+		g.nl(2)
+	}
+	if f.prop {
+		g.Printf("@property\n")
+	}
+	g.Printf("def %s(%s):\n", f.pyName(g.pkg.naming), strings.Join(params, ", "))
+	g.Indent()
+	g.genDoc(f.Doc())
+	for i, p := range cf.params {
+		if p.typ != nil && (recv == "" || i > 0) {
+			g.genConv(p.typ, pyName(p.name))
+		}
+	}
+	call := fmt.Sprintf("_lib.%s(%s)", fct, strings.Join(args, ", "))
+	switch {
+	case errp && cf.ret != nil:
+		g.Printf("_err = ffi.new(\"char**\")\n")
+		g.Printf("_res = %s\n", call)
+		g.Printf("_check(_err)\n")
+		g.Printf("return %s\n", g.topy(cf.ret, "_res"))
+	case errp:
+		g.Printf("_err = ffi.new(\"char**\")\n")
+		g.Printf("%s\n", call)
+		g.Printf("_check(_err)\n")
+	case cf.ret != nil:
+		g.Printf("return %s\n", g.topy(cf.ret, call))
+	default:
+		g.Printf("%s\n", call)
+	}
+	g.Outdent()
+}
+
+// pyKeywords lists the python 2 and 3 keywords, which can not be used as
+// parameter names.
+var pyKeywords = map[string]bool{
+	"and": true, "as": true, "assert": true, "async": true, "await": true,
+	"break": true, "class": true, "continue": true, "def": true, "del": true,
+	"elif": true, "else": true, "except": true, "exec": true, "finally": true,
+	"for": true, "from": true, "global": true, "if": true, "import": true,
+	"in": true, "is": true, "lambda": true, "nonlocal": true, "not": true,
+	"or": true, "pass": true, "print": true, "raise": true, "return": true,
+	"try": true, "while": true, "with": true, "yield": true,
+	"False": true, "None": true, "True": true,
+}
+
+// pyName returns a python identifier for the Go identifier name.
+func pyName(name string) string {
+	if pyKeywords[name] {
+		return name + "_"
+	}
+	return name
+}
+
+// pyQuote returns a python string literal for s, valid in python 2 and 3.
+func pyQuote(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\t':
+			buf.WriteString(`\t`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&buf, `\x%02x`, c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// pyDocString returns a python docstring for doc.
+func pyDocString(doc string) string {
+	doc = strings.TrimSpace(doc)
+	if strings.Contains(doc, `"""`) || strings.Contains(doc, `\`) || !isASCII(doc) {
+		return pyQuote(doc)
+	}
+	lines := strings.Split(doc, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	doc = strings.Join(lines, "\n")
+	if strings.HasSuffix(doc, `"`) {
+		doc += "\n"
+	}
+	return `"""` + doc + `"""`
+}
+
+// isASCII reports whether s only holds ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// pyTuple returns the python tuple of the python expressions elems.
+func pyTuple(elems []string) string {
+	switch len(elems) {
+	case 0:
+		return ""
+	case 1:
+		return elems[0] + ","
+	}
+	return strings.Join(elems, ", ")
+}
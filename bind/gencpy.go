@@ -26,6 +26,9 @@ const (
 #include "memoryobject.h"
 #include "bufferobject.h"
 
+#include <complex.h>
+#include <ctype.h>
+
 // header exported from 'go tool cgo'
 #include "%[3]s.h"
 
@@ -135,6 +138,9 @@ cgopy_cnv_c2py_float32(GoFloat32 *addr) {
 static int
 cgopy_cnv_py2c_complex64(PyObject *o, GoComplex64 *addr) {
 	Py_complex v = PyComplex_AsCComplex(o);
+	if (v.real == -1.0 && PyErr_Occurred()) {
+		return 0;
+	}
 	*addr = v.real + v.imag * _Complex_I;
 	return 1;
 }
@@ -147,6 +153,9 @@ cgopy_cnv_c2py_complex64(GoComplex64 *addr) {
 static int
 cgopy_cnv_py2c_complex128(PyObject *o, GoComplex128 *addr) {
 	Py_complex v = PyComplex_AsCComplex(o);
+	if (v.real == -1.0 && PyErr_Occurred()) {
+		return 0;
+	}
 	*addr = v.real + v.imag * _Complex_I;
 	return 1;
 }
@@ -155,6 +164,179 @@ static PyObject*
 cgopy_cnv_c2py_complex128(GoComplex128 *addr) {
 	return PyComplex_FromDoubles(creal(*addr), cimag(*addr));
 }
+
+// helpers for struct <-> dict conversions
+
+// cgopy_err_prefix prepends prefix to the message of the current exception.
+static void
+cgopy_err_prefix(const char *prefix) {
+	PyObject *exc = NULL, *val = NULL, *tb = NULL;
+	PyObject *msg = NULL;
+	PyErr_Fetch(&exc, &val, &tb);
+	if (exc == NULL) {
+		return;
+	}
+	PyErr_NormalizeException(&exc, &val, &tb);
+	msg = (val != NULL) ? PyObject_Str(val) : NULL;
+	if (msg == NULL) {
+		PyErr_Clear();
+		PyErr_Format(exc, "%%s", prefix);
+	} else {
+		PyErr_Format(exc, "%%s%%s", prefix, PyString_AsString(msg));
+	}
+	Py_XDECREF(msg);
+	Py_XDECREF(exc);
+	Py_XDECREF(val);
+	Py_XDECREF(tb);
+}
+
+// cgopy_err_field records the name of the struct field which failed to
+// convert in the message of the current exception.
+// Errors from nested structs are reported with a dotted path.
+static void
+cgopy_err_field(const char *field) {
+	PyObject *exc = NULL, *val = NULL, *tb = NULL;
+	PyObject *msg = NULL;
+	const char *str = NULL;
+	PyErr_Fetch(&exc, &val, &tb);
+	if (exc == NULL) {
+		return;
+	}
+	PyErr_NormalizeException(&exc, &val, &tb);
+	msg = (val != NULL) ? PyObject_Str(val) : NULL;
+	str = (msg != NULL) ? PyString_AsString(msg) : NULL;
+	if (str == NULL) {
+		PyErr_Clear();
+		PyErr_Format(exc, "field '%%s': invalid value", field);
+	} else if (strncmp(str, "field '", 7) == 0) {
+		PyErr_Format(exc, "field '%%s.%%s", field, str+7);
+	} else {
+		PyErr_Format(exc, "field '%%s': %%s", field, str);
+	}
+	Py_XDECREF(msg);
+	Py_XDECREF(exc);
+	Py_XDECREF(val);
+	Py_XDECREF(tb);
+}
+
+// helpers for enums
+
+// minimal substitute for enum.IntEnum and enum.IntFlag, used when the enum
+// module is not available (python < 3.4 without the enum34 backport.)
+static const char *cgopy_enum_fallback =
+	"class EnumMeta(type):\n"
+	"    def __iter__(cls):\n"
+	"        return iter(cls._members_)\n"
+	"    def __len__(cls):\n"
+	"        return len(cls._members_)\n"
+	"    def __getitem__(cls, name):\n"
+	"        return cls._names_[name]\n"
+	"    def __call__(cls, value):\n"
+	"        if value in cls._values_:\n"
+	"            return cls._values_[value]\n"
+	"        if cls._flag_ and value > 0:\n"
+	"            ms = [m for m in cls._members_ if m & value == m]\n"
+	"            if sum(ms) == value:\n"
+	"                return cls._new_('|'.join(m.name for m in ms), value)\n"
+	"        raise ValueError('%%r is not a valid %%s' %% (value, cls.__name__))\n"
+	"    def _new_(cls, name, value):\n"
+	"        m = int.__new__(cls, value)\n"
+	"        m._name_ = name\n"
+	"        return m\n"
+	"\n"
+	"def make_enum(name, members, module, flag):\n"
+	"    ns = {\n"
+	"        '__module__': module,\n"
+	"        '__repr__': lambda self: '<%%s.%%s: %%d>' %% (type(self).__name__, self._name_, self),\n"
+	"        '__str__': lambda self: '%%s.%%s' %% (type(self).__name__, self._name_),\n"
+	"        'name': property(lambda self: self._name_),\n"
+	"        'value': property(lambda self: int(self)),\n"
+	"        '_flag_': flag, '_members_': [], '_names_': {}, '_values_': {},\n"
+	"    }\n"
+	"    cls = EnumMeta(name, (int,), ns)\n"
+	"    for n, v in members:\n"
+	"        m = cls._new_(n, v)\n"
+	"        cls._members_.append(m)\n"
+	"        cls._names_[n] = m\n"
+	"        cls._values_[v] = m\n"
+	"        setattr(cls, n, m)\n"
+	"    return cls\n";
+
+// cgopy_new_enum creates a new enum class from a list of (name, value)
+// pairs, using enum.IntFlag when flag is set and enum.IntEnum otherwise.
+static PyObject*
+cgopy_new_enum(const char *name, const char *module, PyObject *members, int flag) {
+	PyObject *enum_mod = NULL, *base = NULL, *kwds = NULL, *args = NULL;
+	PyObject *cls = NULL;
+
+	enum_mod = PyImport_ImportModule("enum");
+	if (enum_mod == NULL) {
+		PyObject *globals = NULL, *ret = NULL, *make = NULL;
+		if (!PyErr_ExceptionMatches(PyExc_ImportError)) {
+			return NULL;
+		}
+		PyErr_Clear();
+		globals = PyDict_New();
+		if (globals == NULL) {
+			return NULL;
+		}
+		PyDict_SetItemString(globals, "__builtins__", PyEval_GetBuiltins());
+		ret = PyRun_String(cgopy_enum_fallback, Py_file_input, globals, globals);
+		if (ret == NULL) {
+			Py_DECREF(globals);
+			return NULL;
+		}
+		Py_DECREF(ret);
+		make = PyDict_GetItemString(globals, "make_enum");
+		cls = PyObject_CallFunction(make, "sOsi", name, members, module, flag);
+		Py_DECREF(globals);
+		return cls;
+	}
+
+	if (flag && PyObject_HasAttrString(enum_mod, "IntFlag")) {
+		base = PyObject_GetAttrString(enum_mod, "IntFlag");
+	} else {
+		base = PyObject_GetAttrString(enum_mod, "IntEnum");
+	}
+	Py_DECREF(enum_mod);
+	if (base == NULL) {
+		return NULL;
+	}
+	args = Py_BuildValue("(sO)", name, members);
+	kwds = Py_BuildValue("{ss}", "module", module);
+	if (args != NULL && kwds != NULL) {
+		cls = PyObject_Call(base, args, kwds);
+	}
+	Py_XDECREF(args);
+	Py_XDECREF(kwds);
+	Py_DECREF(base);
+	return cls;
+}
+
+// cgopy_enum_name returns name if it is a valid python identifier which is
+// not already used by a member of the (name, value) list members.
+// It returns def otherwise.
+static const char*
+cgopy_enum_name(PyObject *members, const char *name, const char *def) {
+	Py_ssize_t i = 0;
+	const char *c = name;
+	if (name == NULL || !(isalpha(*c) || *c == '_')) {
+		return def;
+	}
+	for (c++; *c != '\0'; c++) {
+		if (!(isalnum(*c) || *c == '_')) {
+			return def;
+		}
+	}
+	for (i = 0; i < PyList_Size(members); i++) {
+		PyObject *item = PyList_GetItem(members, i);
+		const char *n = PyString_AsString(PyTuple_GetItem(item, 0));
+		if (n != NULL && strcmp(n, name) == 0) {
+			return def;
+		}
+	}
+	return name;
+}
 `
 )
 
@@ -166,7 +348,8 @@ type cpyGen struct {
 	pkg  *Package
 	err  ErrorList
 
-	lang int // c-python api version (2,3)
+	lang int    // c-python api version (2,3)
+	name string // name of the python module
 }
 
 func (g *cpyGen) gen() error {
@@ -211,14 +394,16 @@ func (g *cpyGen) gen() error {
 		g.genVar(v)
 	}
 
+	if len(g.pkg.vars) > 0 {
+		g.genModuleType()
+	}
+
 	g.impl.Printf("\n/* functions for package %s */\n", g.pkg.pkg.Name())
 	g.impl.Printf("static PyMethodDef cpy_%s_methods[] = {\n", g.pkg.pkg.Name())
 	g.impl.Indent()
 	for _, f := range g.pkg.funcs {
-		name := f.GoName()
-		//obj := scope.Lookup(name)
 		g.impl.Printf("{%[1]q, %[2]s, METH_VARARGS, %[3]q},\n",
-			name, "cpy_func_"+f.ID(), f.Doc(),
+			f.pyName(g.pkg.naming), "cpy_func_"+f.ID(), f.Doc(),
 		)
 	}
 	// expose ctors at module level
@@ -226,28 +411,27 @@ func (g *cpyGen) gen() error {
 	// -> problem is if one has 2 or more ctors with exactly the same signature.
 	for _, s := range g.pkg.structs {
 		for _, f := range s.ctors {
-			name := f.GoName()
-			//obj := scope.Lookup(name)
 			g.impl.Printf("{%[1]q, %[2]s, METH_VARARGS, %[3]q},\n",
-				name, "cpy_func_"+f.ID(), f.Doc(),
+				f.pyName(g.pkg.naming), "cpy_func_"+f.ID(), f.Doc(),
 			)
 		}
 	}
 
 	for _, c := range g.pkg.consts {
-		name := c.GoName()
+		if _, big := c.bigInt(); big {
+			continue
+		}
 		g.impl.Printf("{%[1]q, %[2]s, METH_VARARGS, %[3]q},\n",
-			"Get"+name, "cpy_func_"+c.id+"_get", c.Doc(),
+			g.pkg.naming.accessor("get", c.GoName(), c.alias), "cpy_func_"+c.id+"_get", c.Doc(),
 		)
 	}
 
 	for _, v := range g.pkg.vars {
-		name := v.Name()
 		g.impl.Printf("{%[1]q, %[2]s, METH_VARARGS, %[3]q},\n",
-			"Get"+name, "cpy_func_"+v.id+"_get", v.doc,
+			g.pkg.naming.accessor("get", v.Name(), v.alias), "cpy_func_"+v.id+"_get", v.doc,
 		)
 		g.impl.Printf("{%[1]q, %[2]s, METH_VARARGS, %[3]q},\n",
-			"Set"+name, "cpy_func_"+v.id+"_set", v.doc,
+			g.pkg.naming.accessor("set", v.Name(), v.alias), "cpy_func_"+v.id+"_set", v.doc,
 		)
 	}
 
@@ -255,7 +439,7 @@ func (g *cpyGen) gen() error {
 	g.impl.Outdent()
 	g.impl.Printf("};\n\n")
 
-	g.impl.Printf("PyMODINIT_FUNC\ninit%[1]s(void)\n{\n", g.pkg.pkg.Name())
+	g.impl.Printf("PyMODINIT_FUNC\ninit%[1]s(void)\n{\n", g.name)
 	g.impl.Indent()
 	g.impl.Printf("PyObject *module = NULL;\n\n")
 
@@ -273,11 +457,18 @@ func (g *cpyGen) gen() error {
 		)
 	}
 
-	g.impl.Printf("module = Py_InitModule3(%[1]q, cpy_%[1]s_methods, %[2]q);\n\n",
+	g.impl.Printf("module = Py_InitModule3(%[1]q, cpy_%[2]s_methods, %[3]q);\n\n",
+		g.name,
 		g.pkg.pkg.Name(),
 		g.pkg.doc.Doc,
 	)
 
+	if len(g.pkg.vars) > 0 {
+		g.impl.Printf("/* expose package variables as module attributes */\n")
+		g.impl.Printf("if (PyType_Ready(&cpy_%s_ModuleType) < 0) { return; }\n", g.pkg.pkg.Name())
+		g.impl.Printf("Py_TYPE(module) = &cpy_%s_ModuleType;\n\n", g.pkg.pkg.Name())
+	}
+
 	for _, n := range g.pkg.syms.names() {
 		sym := g.pkg.syms.sym(n)
 		if !sym.isType() {
@@ -289,6 +480,37 @@ func (g *cpyGen) gen() error {
 			sym.cpyname,
 		)
 	}
+
+	if len(g.pkg.consts) > 0 {
+		g.impl.Printf("/* constants */\n")
+		g.impl.Printf("{\n")
+		g.impl.Indent()
+		g.impl.Printf("PyObject *o = NULL;\n")
+		for _, c := range g.pkg.consts {
+			switch val, big := c.bigInt(); {
+			case big:
+				g.impl.Printf("o = PyLong_FromString(%q, NULL, 10);\n", val)
+			case c.sym.isNamed():
+				// values of named types are exposed as instances of their type.
+				g.impl.Printf("{\n")
+				g.impl.Indent()
+				g.impl.Printf("%s c_%s = cgo_func_%s_get();\n", c.sym.cgoname, c.id, c.id)
+				g.impl.Printf("o = %s(&c_%s);\n", c.sym.c2py, c.id)
+				g.impl.Outdent()
+				g.impl.Printf("}\n")
+			default:
+				g.impl.Printf("o = cpy_func_%s_get(NULL, NULL);\n", c.id)
+			}
+			g.impl.Printf("if (o == NULL) { return; }\n")
+			g.impl.Printf("PyModule_AddObject(module, %q, o);\n", c.pyName(g.pkg.naming))
+		}
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+	}
+
+	for _, e := range g.pkg.enums {
+		g.genEnumInit(e)
+	}
 	g.impl.Outdent()
 	g.impl.Printf("}\n\n")
 
@@ -299,7 +521,69 @@ func (g *cpyGen) gen() error {
 	return nil
 }
 
+// genEnumInit generates the creation of the enum class for e during the
+// module initialization, and replaces the module attributes for the type
+// and its constants with the enum class and its members.
+func (g *cpyGen) genEnumInit(e Enum) {
+	g.impl.Printf("\n/* enum %s */\n", e.sym.gofmt())
+	g.impl.Printf("{\n")
+	g.impl.Indent()
+	g.impl.Printf("PyObject *members = PyList_New(0);\n")
+	g.impl.Printf("PyObject *cls = NULL;\n")
+	g.impl.Printf("PyObject *o = NULL;\n")
+	g.impl.Printf("if (members == NULL) { return; }\n")
+	for _, c := range e.consts {
+		g.impl.Printf("{\n")
+		g.impl.Indent()
+		g.impl.Printf("const char *name = %q;\n", c.pyName(g.pkg.naming))
+		g.impl.Printf("PyObject *pyname = NULL;\n")
+		g.impl.Printf("PyObject *item = NULL;\n")
+		if e.str {
+			g.impl.Printf("GoString s = cgo_func_%s_enum_name(cgo_func_%s_get());\n", e.ID(), c.id)
+			g.impl.Printf("pyname = cgopy_cnv_c2py_string(&s);\n")
+			g.impl.Printf("if (pyname == NULL) { return; }\n")
+			g.impl.Printf("name = cgopy_enum_name(members, PyString_AsString(pyname), name);\n")
+		}
+		g.impl.Printf(
+			"item = Py_BuildValue(\"(sN)\", name, PyInt_FromString(%q, NULL, 10));\n",
+			c.obj.Val().String(),
+		)
+		g.impl.Printf("Py_XDECREF(pyname);\n")
+		g.impl.Printf("if (item == NULL || PyList_Append(members, item) < 0) { return; }\n")
+		g.impl.Printf("Py_DECREF(item);\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+	}
+	flag := 0
+	if e.flag {
+		flag = 1
+	}
+	g.impl.Printf("cls = cgopy_new_enum(%q, %q, members, %d);\n",
+		e.GoName(),
+		g.pkg.pkg.Name(),
+		flag,
+	)
+	g.impl.Printf("Py_DECREF(members);\n")
+	g.impl.Printf("if (cls == NULL) { return; }\n")
+	g.impl.Printf("cpy_enum_%s = cls;\n", e.ID())
+	g.impl.Printf("Py_INCREF(cls);\n")
+	g.impl.Printf("PyModule_AddObject(module, %q, cls);\n", e.GoName())
+	for _, c := range e.consts {
+		g.impl.Printf(
+			"o = PyObject_CallFunction(cls, \"(N)\", PyInt_FromString(%q, NULL, 10));\n",
+			c.obj.Val().String(),
+		)
+		g.impl.Printf("if (o == NULL) { return; }\n")
+		g.impl.Printf("PyModule_AddObject(module, %q, o);\n", c.pyName(g.pkg.naming))
+	}
+	g.impl.Outdent()
+	g.impl.Printf("}\n")
+}
+
 func (g *cpyGen) genConst(o Const) {
+	if _, big := o.bigInt(); big {
+		return
+	}
 	g.genFunc(o.f)
 }
 
@@ -339,6 +623,106 @@ func (g *cpyGen) genVar(v Var) {
 	}
 }
 
+// genModuleType generates a subclass of the module type whose instances
+// forward the accesses to the package variables to their getters and setters,
+// so the variables can be read and assigned as plain module attributes.
+func (g *cpyGen) genModuleType() {
+	pkgname := g.pkg.pkg.Name()
+
+	g.impl.Printf("\n/* module type for package %s */\n", pkgname)
+	g.impl.Printf("static PyObject*\n")
+	g.impl.Printf("cpy_%s_module_getattro(PyObject *self, PyObject *name) {\n", pkgname)
+	g.impl.Indent()
+	g.impl.Printf("const char *n = PyString_Check(name) ? PyString_AsString(name) : NULL;\n")
+	g.impl.Printf("if (n != NULL) {\n")
+	g.impl.Indent()
+	for _, v := range g.pkg.vars {
+		g.impl.Printf("if (strcmp(n, %q) == 0) {\n", v.pyName(g.pkg.naming))
+		g.impl.Indent()
+		g.impl.Printf("return cpy_func_%s_get(NULL, NULL);\n", v.id)
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+	}
+	g.impl.Outdent()
+	g.impl.Printf("}\n")
+	g.impl.Printf("return PyObject_GenericGetAttr(self, name);\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	g.impl.Printf("static int\n")
+	g.impl.Printf("cpy_%s_module_setattro(PyObject *self, PyObject *name, PyObject *value) {\n", pkgname)
+	g.impl.Indent()
+	g.impl.Printf("const char *n = PyString_Check(name) ? PyString_AsString(name) : NULL;\n")
+	g.impl.Printf("PyObject *args = NULL;\n")
+	g.impl.Printf("PyObject *ret = NULL;\n")
+	g.impl.Printf("if (n != NULL) {\n")
+	g.impl.Indent()
+	for _, v := range g.pkg.vars {
+		g.impl.Printf("if (strcmp(n, %q) == 0) {\n", v.pyName(g.pkg.naming))
+		g.impl.Indent()
+		g.impl.Printf("if (value == NULL) {\n")
+		g.impl.Indent()
+		g.impl.Printf(
+			"PyErr_SetString(PyExc_TypeError, \"cannot delete '%s' attribute\");\n",
+			v.pyName(g.pkg.naming),
+		)
+		g.impl.Printf("return -1;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+		g.impl.Printf("args = PyTuple_Pack(1, value);\n")
+		g.impl.Printf("if (args == NULL) { return -1; }\n")
+		g.impl.Printf("ret = cpy_func_%s_set(NULL, args);\n", v.id)
+		g.impl.Printf("Py_DECREF(args);\n")
+		g.impl.Printf("if (ret == NULL) { return -1; }\n")
+		g.impl.Printf("Py_DECREF(ret);\n")
+		g.impl.Printf("return 0;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+	}
+	g.impl.Outdent()
+	g.impl.Printf("}\n")
+	g.impl.Printf("return PyObject_GenericSetAttr(self, name, value);\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	g.impl.Printf("static PyTypeObject cpy_%s_ModuleType = {\n", pkgname)
+	g.impl.Indent()
+	g.impl.Printf("PyObject_HEAD_INIT(NULL)\n")
+	g.impl.Printf("0,\t/*ob_size*/\n")
+	g.impl.Printf("\"module\",\t/*tp_name*/\n")
+	g.impl.Printf("0,\t/*tp_basicsize*/\n")
+	g.impl.Printf("0,\t/*tp_itemsize*/\n")
+	g.impl.Printf("0,\t/*tp_dealloc*/\n")
+	g.impl.Printf("0,\t/*tp_print*/\n")
+	g.impl.Printf("0,\t/*tp_getattr*/\n")
+	g.impl.Printf("0,\t/*tp_setattr*/\n")
+	g.impl.Printf("0,\t/*tp_compare*/\n")
+	g.impl.Printf("0,\t/*tp_repr*/\n")
+	g.impl.Printf("0,\t/*tp_as_number*/\n")
+	g.impl.Printf("0,\t/*tp_as_sequence*/\n")
+	g.impl.Printf("0,\t/*tp_as_mapping*/\n")
+	g.impl.Printf("0,\t/*tp_hash */\n")
+	g.impl.Printf("0,\t/*tp_call*/\n")
+	g.impl.Printf("0,\t/*tp_str*/\n")
+	g.impl.Printf("cpy_%s_module_getattro,\t/*tp_getattro*/\n", pkgname)
+	g.impl.Printf("cpy_%s_module_setattro,\t/*tp_setattro*/\n", pkgname)
+	g.impl.Printf("0,\t/*tp_as_buffer*/\n")
+	g.impl.Printf("Py_TPFLAGS_DEFAULT,\t/*tp_flags*/\n")
+	g.impl.Printf("0,\t/* tp_doc */\n")
+	g.impl.Printf("0,\t/* tp_traverse */\n")
+	g.impl.Printf("0,\t/* tp_clear */\n")
+	g.impl.Printf("0,\t/* tp_richcompare */\n")
+	g.impl.Printf("0,\t/* tp_weaklistoffset */\n")
+	g.impl.Printf("0,\t/* tp_iter */\n")
+	g.impl.Printf("0,\t/* tp_iternext */\n")
+	g.impl.Printf("0,\t/* tp_methods */\n")
+	g.impl.Printf("0,\t/* tp_members */\n")
+	g.impl.Printf("0,\t/* tp_getset */\n")
+	g.impl.Printf("&PyModule_Type,\t/* tp_base */\n")
+	g.impl.Outdent()
+	g.impl.Printf("};\n\n")
+}
+
 func (g *cpyGen) genPreamble() {
 	n := g.pkg.pkg.Name()
 	g.decl.Printf(cPreamble, n, g.pkg.pkg.Path(), filepath.Base(n))
@@ -6,9 +6,8 @@ package bind
 
 import (
 	"fmt"
+	"go/types"
 	"strings"
-
-	"golang.org/x/tools/go/types"
 )
 
 func (g *cpyGen) _genFunc(sym *symbol, fsym *symbol) {
@@ -131,14 +130,26 @@ func (g *cpyGen) _genFunc(sym *symbol, fsym *symbol) {
 		g.impl.Printf("}\n\n")
 	}
 
+	parent := ""
+	if isMethod {
+		parent = sym.goname
+	}
+	nogil := g.pkg.releaseGIL(g.pkg.declKey(parent, fsym.goobj.Name()))
+	if nogil {
+		g.impl.Printf("Py_BEGIN_ALLOW_THREADS\n")
+	}
 	if nres > 0 {
 		g.impl.Printf("ret = ")
 	}
 
-	g.impl.Printf("cgo_func_%[1]s(%[2]s);\n\n",
+	g.impl.Printf("cgo_func_%[1]s(%[2]s);\n",
 		fsym.id,
 		strings.Join(funcArgs, ", "),
 	)
+	if nogil {
+		g.impl.Printf("Py_END_ALLOW_THREADS\n")
+	}
+	g.impl.Printf("\n")
 
 	if nres <= 0 {
 		g.impl.Printf("Py_INCREF(Py_None);\nreturn Py_None;\n")
@@ -271,10 +282,17 @@ func (g *cpyGen) genFuncBody(f Func) {
 		g.impl.Printf("\n")
 	}
 
+	// the GIL may be released, as the go func never calls back into python.
+	if f.nogil {
+		g.impl.Printf("Py_BEGIN_ALLOW_THREADS\n")
+	}
 	if len(res) > 0 {
 		g.impl.Printf("c_gopy_ret = ")
 	}
 	g.impl.Printf("cgo_func_%[1]s(%[2]s);\n", id, strings.Join(funcArgs, ", "))
+	if f.nogil {
+		g.impl.Printf("Py_END_ALLOW_THREADS\n")
+	}
 
 	g.impl.Printf("\n")
 
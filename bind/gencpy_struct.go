@@ -6,9 +6,8 @@ package bind
 
 import (
 	"fmt"
+	"go/types"
 	"strings"
-
-	"golang.org/x/tools/go/types"
 )
 
 func (g *cpyGen) genStruct(cpy Struct) {
@@ -86,6 +85,7 @@ func (g *cpyGen) genStruct(cpy Struct) {
 
 	g.genStructConverters(cpy)
 	g.genStructTypeCheck(cpy)
+	g.genStructNative(cpy)
 
 }
 
@@ -118,8 +118,7 @@ func (g *cpyGen) genStructInit(cpy Struct) {
 	numFields := cpy.Struct().NumFields()
 	numPublic := numFields
 	for i := 0; i < cpy.Struct().NumFields(); i++ {
-		f := cpy.Struct().Field(i)
-		if !f.Exported() {
+		if g.pkg.pyField(cpy.Struct(), i, g.pkg.naming).hidden {
 			numPublic--
 			continue
 		}
@@ -130,20 +129,19 @@ func (g *cpyGen) genStructInit(cpy Struct) {
 		g.impl.Printf("static char *kwlist[] = {\n")
 		g.impl.Indent()
 		for i := 0; i < numFields; i++ {
-			field := cpy.Struct().Field(i)
-			if !field.Exported() {
+			field := g.pkg.pyField(cpy.Struct(), i, g.pkg.naming)
+			if field.hidden {
 				continue
 			}
-			kwds[field.Name()] = i
-			g.impl.Printf("%q, /* py_kwd_%03d */\n", field.Name(), i)
+			kwds[field.name] = i
+			g.impl.Printf("%q, /* py_kwd_%03d */\n", field.name, i)
 		}
 		g.impl.Printf("NULL\n")
 		g.impl.Outdent()
 		g.impl.Printf("};\n")
 
 		for i := 0; i < numFields; i++ {
-			field := cpy.Struct().Field(i)
-			if !field.Exported() {
+			if g.pkg.pyField(cpy.Struct(), i, g.pkg.naming).hidden {
 				continue
 			}
 			g.impl.Printf("PyObject *py_kwd_%03d = NULL;\n", i)
@@ -166,8 +164,7 @@ func (g *cpyGen) genStructInit(cpy Struct) {
 		format := []string{"|"}
 		addrs := []string{}
 		for i := 0; i < numFields; i++ {
-			field := cpy.Struct().Field(i)
-			if !field.Exported() {
+			if g.pkg.pyField(cpy.Struct(), i, g.pkg.naming).hidden {
 				continue
 			}
 			format = append(format, "O")
@@ -183,8 +180,7 @@ func (g *cpyGen) genStructInit(cpy Struct) {
 		g.impl.Printf("}\n\n")
 
 		for i := 0; i < numFields; i++ {
-			field := cpy.Struct().Field(i)
-			if !field.Exported() {
+			if g.pkg.pyField(cpy.Struct(), i, g.pkg.naming).hidden {
 				continue
 			}
 			g.impl.Printf("if (py_kwd_%03d != NULL) {\n", i)
@@ -209,8 +205,7 @@ func (g *cpyGen) genStructInit(cpy Struct) {
 	g.impl.Printf("\ncpy_label_%s_init_fail:\n", cpy.sym.cpyname)
 	g.impl.Indent()
 	for i := 0; i < numFields; i++ {
-		field := cpy.Struct().Field(i)
-		if !field.Exported() {
+		if g.pkg.pyField(cpy.Struct(), i, g.pkg.naming).hidden {
 			continue
 		}
 		g.impl.Printf("Py_XDECREF(py_kwd_%03d);\n", i)
@@ -226,28 +221,48 @@ func (g *cpyGen) genStructMembers(cpy Struct) {
 
 	g.decl.Printf("\n/* tp_getset for %s.%v */\n", pkgname, cpy.GoName())
 	for i := 0; i < typ.NumFields(); i++ {
-		f := typ.Field(i)
-		if !f.Exported() {
+		if g.pkg.pyField(typ, i, g.pkg.naming).hidden {
 			continue
 		}
+		f := typ.Field(i)
 		g.genStructMemberGetter(cpy, i, f)
 		g.genStructMemberSetter(cpy, i, f)
 	}
+	for _, m := range cpy.meths {
+		if m.prop {
+			g.genStructProperty(cpy, m)
+		}
+	}
 
 	g.impl.Printf("\n/* tp_getset for %s.%v */\n", pkgname, cpy.GoName())
 	g.impl.Printf("static PyGetSetDef %s_getsets[] = {\n", cpy.sym.cpyname)
 	g.impl.Indent()
 	for i := 0; i < typ.NumFields(); i++ {
-		f := typ.Field(i)
-		if !f.Exported() {
+		pf := g.pkg.pyField(typ, i, g.pkg.naming)
+		if pf.hidden {
 			continue
 		}
-		doc := "doc for " + f.Name() // FIXME(sbinet) retrieve doc for fields
-		g.impl.Printf("{%q, ", f.Name())
+		doc := cpy.FieldDoc(i)
+		g.impl.Printf("{%q, ", pf.name)
 		g.impl.Printf("(getter)cpy_func_%[1]s_getter_%[2]d, ", cpy.sym.id, i+1)
-		g.impl.Printf("(setter)cpy_func_%[1]s_setter_%[2]d, ", cpy.sym.id, i+1)
+		if pf.readonly {
+			g.impl.Printf("(setter)NULL, ")
+		} else {
+			g.impl.Printf("(setter)cpy_func_%[1]s_setter_%[2]d, ", cpy.sym.id, i+1)
+		}
 		g.impl.Printf("%q, NULL},\n", doc)
 	}
+	for _, m := range cpy.meths {
+		if !m.prop {
+			continue
+		}
+		g.impl.Printf(
+			"{%[1]q, (getter)cpy_func_%[2]s_property, (setter)NULL, %[3]q, NULL},\n",
+			m.pyName(g.pkg.naming),
+			m.ID(),
+			m.Doc(),
+		)
+	}
 	g.impl.Printf("{NULL} /* Sentinel */\n")
 	g.impl.Outdent()
 	g.impl.Printf("};\n\n")
@@ -331,6 +346,34 @@ func (g *cpyGen) genStructMemberGetter(cpy Struct, i int, f types.Object) {
 
 }
 
+// genStructProperty generates the getter of the property calling the method
+// m of the struct cpy.
+func (g *cpyGen) genStructProperty(cpy Struct, m Func) {
+	g.decl.Printf("\n/* property %[1]s.%[2]s.%[3]s */\n",
+		cpy.Package().Name(), cpy.sym.goname, m.GoName(),
+	)
+	g.decl.Printf("static PyObject*\n")
+	g.decl.Printf(
+		"cpy_func_%[1]s_property(%[2]s *self, void *closure);\n",
+		m.ID(),
+		cpy.sym.cpyname,
+	)
+
+	g.impl.Printf("\n/* property %[1]s.%[2]s.%[3]s */\n",
+		cpy.Package().Name(), cpy.sym.goname, m.GoName(),
+	)
+	g.impl.Printf("static PyObject*\n")
+	g.impl.Printf(
+		"cpy_func_%[1]s_property(%[2]s *self, void *closure) {\n",
+		m.ID(),
+		cpy.sym.cpyname,
+	)
+	g.impl.Indent()
+	g.impl.Printf("return cpy_func_%[1]s(self, NULL, NULL);\n", m.ID())
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+}
+
 func (g *cpyGen) genStructMemberSetter(cpy Struct, i int, f types.Object) {
 	var (
 		pkg          = cpy.Package()
@@ -339,6 +382,7 @@ func (g *cpyGen) genStructMemberSetter(cpy Struct, i int, f types.Object) {
 		ifield       = newVar(pkg, ft, f.Name(), "ret", "")
 		cgo_fsetname = fmt.Sprintf("cgo_func_%[1]s_setter_%[2]d", cpy.sym.id, i+1)
 		cpy_fsetname = fmt.Sprintf("cpy_func_%[1]s_setter_%[2]d", cpy.sym.id, i+1)
+		pyname       = g.pkg.pyField(cpy.Struct(), i, g.pkg.naming).name
 	)
 
 	g.decl.Printf("\n/* setter for %[1]s.%[2]s.%[3]s */\n",
@@ -368,7 +412,7 @@ func (g *cpyGen) genStructMemberSetter(cpy Struct, i int, f types.Object) {
 	g.impl.Indent()
 	g.impl.Printf(
 		"PyErr_SetString(PyExc_TypeError, \"cannot delete '%[1]s' attribute\");\n",
-		f.Name(),
+		pyname,
 	)
 	g.impl.Printf("return -1;\n")
 	g.impl.Outdent()
@@ -378,7 +422,7 @@ func (g *cpyGen) genStructMemberSetter(cpy Struct, i int, f types.Object) {
 	g.impl.Indent()
 	g.impl.Printf(
 		"PyErr_SetString(PyExc_TypeError, \"invalid type for '%[1]s' attribute\");\n",
-		f.Name(),
+		pyname,
 	)
 	g.impl.Printf("return -1;\n")
 	g.impl.Outdent()
@@ -409,7 +453,7 @@ func (g *cpyGen) genStructMethods(cpy Struct) {
 	typ := cpy.sym.GoType().(*types.Named)
 	for i := 0; i < typ.NumMethods(); i++ {
 		m := typ.Method(i)
-		if !m.Exported() {
+		if !g.pkg.syms.isExposedMethod(m) {
 			continue
 		}
 		mname := types.ObjectString(m, nil)
@@ -422,28 +466,254 @@ func (g *cpyGen) genStructMethods(cpy Struct) {
 		}
 		g._genFunc(cpy.sym, msym)
 	}
+	g.genStructToDict(cpy)
+	g.genStructFromDict(cpy)
 
 	g.impl.Printf("\n/* methods for %s.%s */\n", pkgname, cpy.GoName())
 	g.impl.Printf("static PyMethodDef %s_methods[] = {\n", cpy.sym.cpyname)
 	g.impl.Indent()
 	for _, m := range cpy.meths {
+		if m.prop {
+			continue
+		}
 		margs := "METH_VARARGS"
 		if len(m.Signature().Params()) == 0 {
 			margs = "METH_NOARGS"
 		}
 		g.impl.Printf(
 			"{%[1]q, (PyCFunction)cpy_func_%[2]s, %[3]s, %[4]q},\n",
-			m.GoName(),
+			m.pyName(g.pkg.naming),
 			m.ID(),
 			margs,
 			m.Doc(),
 		)
 	}
+	g.impl.Printf(
+		"{%[1]q, (PyCFunction)cpy_func_%[2]s_to_dict, METH_NOARGS, %[3]q},\n",
+		"to_dict",
+		cpy.sym.id,
+		"to_dict() -> dict\n\nreturns the content of the value as a dict of native python values",
+	)
+	g.impl.Printf(
+		"{%[1]q, (PyCFunction)cpy_func_%[2]s_from_dict, METH_CLASS | METH_O, %[3]q},\n",
+		"from_dict",
+		cpy.sym.id,
+		"from_dict(d) -> "+cpy.GoName()+"\n\ncreates a new value from the content of the dict d",
+	)
 	g.impl.Printf("{NULL} /* sentinel */\n")
 	g.impl.Outdent()
 	g.impl.Printf("};\n\n")
 }
 
+func (g *cpyGen) genStructToDict(cpy Struct) {
+	pkgname := cpy.Package().Name()
+	typ := cpy.Struct()
+
+	g.decl.Printf("\n/* to_dict for %s.%s */\n", pkgname, cpy.GoName())
+	g.decl.Printf("static PyObject*\n")
+	g.decl.Printf("cpy_func_%[1]s_to_dict(%[2]s *self, PyObject *args);\n",
+		cpy.sym.id,
+		cpy.sym.cpyname,
+	)
+
+	g.impl.Printf("\n/* to_dict for %s.%s */\n", pkgname, cpy.GoName())
+	g.impl.Printf("static PyObject*\n")
+	g.impl.Printf("cpy_func_%[1]s_to_dict(%[2]s *self, PyObject *args) {\n",
+		cpy.sym.id,
+		cpy.sym.cpyname,
+	)
+	g.impl.Indent()
+	g.impl.Printf("PyObject *v = NULL;\n")
+	g.impl.Printf("PyObject *dict = PyDict_New();\n")
+	g.impl.Printf("if (dict == NULL) {\n")
+	g.impl.Indent()
+	g.impl.Printf("return NULL;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	for i := 0; i < typ.NumFields(); i++ {
+		key, ok := g.pkg.dictKey(typ, i, g.pkg.naming)
+		if !ok {
+			continue
+		}
+		f := typ.Field(i)
+		g.impl.Printf("v = cpy_func_%[1]s_getter_%[2]d(self, NULL);\n", cpy.sym.id, i+1)
+		if fsym := g.pkg.syms.symtype(f.Type()); fsym.isWrapped() {
+			g.impl.Printf("if (v != NULL) {\n")
+			g.impl.Indent()
+			g.impl.Printf("PyObject *tmp = cpy_func_%[1]s_to_native(v);\n", fsym.id)
+			g.impl.Printf("Py_DECREF(v);\n")
+			g.impl.Printf("v = tmp;\n")
+			g.impl.Outdent()
+			g.impl.Printf("}\n")
+		}
+		g.impl.Printf("if (v == NULL || PyDict_SetItemString(dict, %q, v) < 0) {\n", key)
+		g.impl.Indent()
+		g.impl.Printf("goto cpy_label_%s_to_dict_fail;\n", cpy.sym.id)
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+		g.impl.Printf("Py_CLEAR(v);\n\n")
+	}
+	g.impl.Printf("return dict;\n")
+	g.impl.Outdent()
+
+	g.impl.Printf("\ncpy_label_%s_to_dict_fail:\n", cpy.sym.id)
+	g.impl.Indent()
+	g.impl.Printf("Py_XDECREF(v);\n")
+	g.impl.Printf("Py_DECREF(dict);\n")
+	g.impl.Printf("return NULL;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+}
+
+func (g *cpyGen) genStructFromDict(cpy Struct) {
+	pkgname := cpy.Package().Name()
+	typ := cpy.Struct()
+
+	g.decl.Printf("\n/* from_dict for %s.%s */\n", pkgname, cpy.GoName())
+	g.decl.Printf("static PyObject*\n")
+	g.decl.Printf("cpy_func_%[1]s_new_from_dict(PyTypeObject *type, PyObject *d);\n",
+		cpy.sym.id,
+	)
+	g.decl.Printf("static PyObject*\n")
+	g.decl.Printf("cpy_func_%[1]s_from_dict(PyObject *type, PyObject *d);\n",
+		cpy.sym.id,
+	)
+
+	g.impl.Printf("\n/* from_dict for %s.%s */\n", pkgname, cpy.GoName())
+	g.impl.Printf("static PyObject*\n")
+	g.impl.Printf("cpy_func_%[1]s_new_from_dict(PyTypeObject *type, PyObject *d) {\n",
+		cpy.sym.id,
+	)
+	g.impl.Indent()
+	g.impl.Printf("PyObject *o = NULL;\n")
+	g.impl.Printf("PyObject *key = NULL;\n")
+	g.impl.Printf("PyObject *value = NULL;\n")
+	g.impl.Printf("Py_ssize_t pos = 0;\n")
+	g.impl.Printf("if (!PyDict_Check(d)) {\n")
+	g.impl.Indent()
+	g.impl.Printf(
+		"PyErr_Format(PyExc_TypeError, \"expected a dict, got %%s\", Py_TYPE(d)->tp_name);\n",
+	)
+	g.impl.Printf("return NULL;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	g.impl.Printf("o = PyObject_CallObject((PyObject*)type, NULL);\n")
+	g.impl.Printf("if (o == NULL) {\n")
+	g.impl.Indent()
+	g.impl.Printf("return NULL;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	g.impl.Printf("while (PyDict_Next(d, &pos, &key, &value)) {\n")
+	g.impl.Indent()
+	g.impl.Printf("const char *k = PyString_Check(key) ? PyString_AsString(key) : NULL;\n")
+	g.impl.Printf("if (k == NULL) {\n")
+	g.impl.Indent()
+	g.impl.Printf(
+		"PyErr_Format(PyExc_TypeError, \"invalid key type (got=%%s, expected a str)\", Py_TYPE(key)->tp_name);\n",
+	)
+	g.impl.Printf("goto cpy_label_%s_from_dict_fail;\n", cpy.sym.id)
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	for i := 0; i < typ.NumFields(); i++ {
+		key, ok := g.pkg.dictKey(typ, i, g.pkg.naming)
+		if !ok {
+			continue
+		}
+		f := typ.Field(i)
+		fsym := g.pkg.syms.symtype(f.Type())
+		g.impl.Printf("if (strcmp(k, %q) == 0) {\n", key)
+		g.impl.Indent()
+		if fsym.isWrapped() {
+			g.impl.Printf("PyObject *v = cpy_func_%[1]s_from_native(value);\n", fsym.id)
+			g.impl.Printf("if (v == NULL) {\n")
+			g.impl.Indent()
+			g.impl.Printf("cgopy_err_field(%q);\n", key)
+			g.impl.Printf("goto cpy_label_%s_from_dict_fail;\n", cpy.sym.id)
+			g.impl.Outdent()
+			g.impl.Printf("}\n")
+			g.impl.Printf(
+				"if (cpy_func_%[1]s_setter_%[2]d((%[3]s*)o, v, NULL)) {\n",
+				cpy.sym.id,
+				i+1,
+				cpy.sym.cpyname,
+			)
+			g.impl.Indent()
+			g.impl.Printf("Py_DECREF(v);\n")
+			g.impl.Printf("cgopy_err_field(%q);\n", key)
+			g.impl.Printf("goto cpy_label_%s_from_dict_fail;\n", cpy.sym.id)
+			g.impl.Outdent()
+			g.impl.Printf("}\n")
+			g.impl.Printf("Py_DECREF(v);\n")
+		} else {
+			g.impl.Printf("if (!%s) {\n", fmt.Sprintf(fsym.pychk, "value"))
+			g.impl.Indent()
+			g.impl.Printf(
+				"PyErr_Format(PyExc_TypeError, \"field '%[1]s': expected %[2]s, got %%s\", Py_TYPE(value)->tp_name);\n",
+				key,
+				fsym.pysig,
+			)
+			g.impl.Printf("goto cpy_label_%s_from_dict_fail;\n", cpy.sym.id)
+			g.impl.Outdent()
+			g.impl.Printf("}\n")
+			g.impl.Printf(
+				"if (cpy_func_%[1]s_setter_%[2]d((%[3]s*)o, value, NULL)) {\n",
+				cpy.sym.id,
+				i+1,
+				cpy.sym.cpyname,
+			)
+			g.impl.Indent()
+			g.impl.Printf("cgopy_err_field(%q);\n", key)
+			g.impl.Printf("goto cpy_label_%s_from_dict_fail;\n", cpy.sym.id)
+			g.impl.Outdent()
+			g.impl.Printf("}\n")
+		}
+		g.impl.Printf("continue;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+	}
+
+	g.impl.Printf("PyErr_Format(PyExc_TypeError, \"unknown field '%%s'\", k);\n")
+	g.impl.Printf("goto cpy_label_%s_from_dict_fail;\n", cpy.sym.id)
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n") // while-loop
+
+	g.impl.Printf("return o;\n")
+	g.impl.Outdent()
+
+	g.impl.Printf("\ncpy_label_%s_from_dict_fail:\n", cpy.sym.id)
+	g.impl.Indent()
+	g.impl.Printf("Py_DECREF(o);\n")
+	g.impl.Printf("return NULL;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	g.impl.Printf("static PyObject*\n")
+	g.impl.Printf("cpy_func_%[1]s_from_dict(PyObject *type, PyObject *d) {\n",
+		cpy.sym.id,
+	)
+	g.impl.Indent()
+	g.impl.Printf(
+		"PyObject *o = cpy_func_%[1]s_new_from_dict((PyTypeObject*)type, d);\n",
+		cpy.sym.id,
+	)
+	g.impl.Printf("if (o == NULL) {\n")
+	g.impl.Indent()
+	g.impl.Printf("cgopy_err_prefix(%q);\n", cpy.GoName()+".from_dict: ")
+	g.impl.Outdent()
+	g.impl.Printf("}\n")
+	g.impl.Printf("return o;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+}
+
+func (g *cpyGen) genStructNative(cpy Struct) {
+	g.genTypeNative(cpy.sym)
+}
+
 func (g *cpyGen) genStructProtocols(cpy Struct) {
 	g.genStructTPStr(cpy)
 }
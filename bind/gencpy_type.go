@@ -6,9 +6,8 @@ package bind
 
 import (
 	"fmt"
+	"go/types"
 	"strings"
-
-	"golang.org/x/tools/go/types"
 )
 
 func (g *cpyGen) genType(sym *symbol) {
@@ -80,6 +79,23 @@ func (g *cpyGen) genType(sym *symbol) {
 		}
 	}
 
+	tpAsNumber := "0"
+	if sym.isBasic() && isComplexType(sym.GoType()) {
+		tpAsNumber = fmt.Sprintf("&%[1]s_tp_as_number", sym.cpyname)
+		switch g.lang {
+		case 2:
+			tpFlags = fmt.Sprintf(
+				"(%s)",
+				strings.Join([]string{
+					"Py_TPFLAGS_DEFAULT",
+					"Py_TPFLAGS_CHECKTYPES",
+				},
+					" |\n ",
+				))
+		case 3:
+		}
+	}
+
 	tpCall := "0"
 	if sym.isSignature() {
 		sig := sym.GoType().Underlying().(*types.Signature)
@@ -102,7 +118,7 @@ func (g *cpyGen) genType(sym *symbol) {
 	g.impl.Printf("0,\t/*tp_setattr*/\n")
 	g.impl.Printf("0,\t/*tp_compare*/\n")
 	g.impl.Printf("0,\t/*tp_repr*/\n")
-	g.impl.Printf("0,\t/*tp_as_number*/\n")
+	g.impl.Printf("%s,\t/*tp_as_number*/\n", tpAsNumber)
 	g.impl.Printf("%s,\t/*tp_as_sequence*/\n", tpAsSequence)
 	g.impl.Printf("0,\t/*tp_as_mapping*/\n")
 	g.impl.Printf("0,\t/*tp_hash */\n")
@@ -135,6 +151,7 @@ func (g *cpyGen) genType(sym *symbol) {
 
 	g.genTypeConverter(sym)
 	g.genTypeTypeCheck(sym)
+	g.genTypeNative(sym)
 }
 
 func (g *cpyGen) genTypeNew(sym *symbol) {
@@ -328,7 +345,67 @@ func (g *cpyGen) genTypeInit(sym *symbol) {
 	case sym.isMap():
 		g.impl.Printf("if (arg != NULL) {\n")
 		g.impl.Indent()
-		//g.impl.Printf("//put map __init__ functions here")
+		if ksym, esym := g.pkg.syms.mapsyms(sym); ksym != nil {
+			bsym := g.pkg.syms.symtype(ksym.GoType().Underlying())
+			g.impl.Printf("if (!PyDict_Check(arg)) {\n")
+			g.impl.Indent()
+			g.impl.Printf("PyErr_SetString(PyExc_TypeError, ")
+			g.impl.Printf("\"%s.__init__ takes a dict as argument\");\n", sym.goname)
+			g.impl.Printf("goto cpy_label_%s_init_fail;\n", sym.id)
+			g.impl.Outdent()
+			g.impl.Printf("}\n\n")
+
+			g.impl.Printf("PyObject *key = NULL;\n")
+			g.impl.Printf("PyObject *value = NULL;\n")
+			g.impl.Printf("Py_ssize_t pos = 0;\n")
+			g.impl.Printf("while (PyDict_Next(arg, &pos, &key, &value)) {\n")
+			g.impl.Indent()
+			g.impl.Printf("%[1]s c_k;\n", ksym.cgoname)
+			g.impl.Printf("%[1]s c_v;\n", esym.cgoname)
+			g.impl.Printf("if (!%[1]s) {\n", fmt.Sprintf(bsym.pychk, "key"))
+			g.impl.Indent()
+			g.impl.Printf(
+				"PyErr_Format(PyExc_TypeError, \"invalid key type (got=%%s, expected a %s)\", Py_TYPE(key)->tp_name);\n",
+				ksym.goname,
+			)
+			g.impl.Printf("goto cpy_label_%s_init_fail;\n", sym.id)
+			g.impl.Outdent()
+			g.impl.Printf("}\n")
+			g.impl.Printf("if (!%[1]s(key, (%[2]s*)&c_k)) {\n", bsym.py2c, bsym.cgoname)
+			g.impl.Indent()
+			g.impl.Printf("goto cpy_label_%s_init_fail;\n", sym.id)
+			g.impl.Outdent()
+			g.impl.Printf("}\n")
+			if esym.isWrapped() {
+				g.impl.Printf("PyObject *v = cpy_func_%[1]s_from_native(value);\n", esym.id)
+				g.impl.Printf("if (v == NULL || !%[1]s(v, &c_v)) {\n", esym.py2c)
+				g.impl.Indent()
+				g.impl.Printf("Py_XDECREF(v);\n")
+				g.impl.Printf("goto cpy_label_%s_init_fail;\n", sym.id)
+				g.impl.Outdent()
+				g.impl.Printf("}\n")
+				g.impl.Printf("cgo_func_%[1]s_set(self->cgopy, c_k, c_v);\n", sym.id)
+				g.impl.Printf("Py_DECREF(v);\n")
+			} else {
+				g.impl.Printf("if (!%[1]s) {\n", fmt.Sprintf(esym.pychk, "value"))
+				g.impl.Indent()
+				g.impl.Printf(
+					"PyErr_Format(PyExc_TypeError, \"invalid type (got=%%s, expected a %s)\", Py_TYPE(value)->tp_name);\n",
+					esym.goname,
+				)
+				g.impl.Printf("goto cpy_label_%s_init_fail;\n", sym.id)
+				g.impl.Outdent()
+				g.impl.Printf("}\n")
+				g.impl.Printf("if (!%[1]s(value, &c_v)) {\n", esym.py2c)
+				g.impl.Indent()
+				g.impl.Printf("goto cpy_label_%s_init_fail;\n", sym.id)
+				g.impl.Outdent()
+				g.impl.Printf("}\n")
+				g.impl.Printf("cgo_func_%[1]s_set(self->cgopy, c_k, c_v);\n", sym.id)
+			}
+			g.impl.Outdent()
+			g.impl.Printf("}\n") // while-loop
+		}
 		g.impl.Outdent()
 		g.impl.Printf("}\n\n") // if-arg
 
@@ -350,7 +427,6 @@ func (g *cpyGen) genTypeInit(sym *symbol) {
 
 	g.impl.Printf("\ncpy_label_%s_init_fail:\n", sym.id)
 	g.impl.Indent()
-	g.impl.Printf("Py_XDECREF(arg);\n")
 	g.impl.Printf("return -1;\n")
 	g.impl.Outdent()
 	g.impl.Printf("}\n\n")
@@ -372,7 +448,7 @@ func (g *cpyGen) genTypeMethods(sym *symbol) {
 		typ := sym.GoType().(*types.Named)
 		for imeth := 0; imeth < typ.NumMethods(); imeth++ {
 			m := typ.Method(imeth)
-			if !m.Exported() {
+			if !g.pkg.syms.isExposedMethod(m) {
 				continue
 			}
 			mname := types.ObjectString(m, nil)
@@ -387,6 +463,25 @@ func (g *cpyGen) genTypeMethods(sym *symbol) {
 			g._genFunc(sym, msym)
 		}
 	}
+	if sym.isBasic() && isComplexType(sym.GoType()) {
+		g.decl.Printf("\n/* __complex__ support for %s */\n", sym.gofmt())
+		g.decl.Printf("static PyObject*\n")
+		g.decl.Printf("cpy_func_%[1]s_complex(%[2]s *self, PyObject *args);\n",
+			sym.id,
+			sym.cpyname,
+		)
+
+		g.impl.Printf("\n/* __complex__ support for %s */\n", sym.gofmt())
+		g.impl.Printf("static PyObject*\n")
+		g.impl.Printf("cpy_func_%[1]s_complex(%[2]s *self, PyObject *args) {\n",
+			sym.id,
+			sym.cpyname,
+		)
+		g.impl.Indent()
+		g.impl.Printf("return PyComplex_FromDoubles(creal(self->cgopy), cimag(self->cgopy));\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+	}
 	g.impl.Printf("\n/* methods for %s */\n", sym.gofmt())
 	g.impl.Printf("static PyMethodDef %s_methods[] = {\n", sym.cpyname)
 	g.impl.Indent()
@@ -394,7 +489,7 @@ func (g *cpyGen) genTypeMethods(sym *symbol) {
 		typ := sym.GoType().(*types.Named)
 		for imeth := 0; imeth < typ.NumMethods(); imeth++ {
 			m := typ.Method(imeth)
-			if !m.Exported() {
+			if !g.pkg.syms.isExposedMethod(m) {
 				continue
 			}
 			mname := types.ObjectString(m, nil)
@@ -406,13 +501,21 @@ func (g *cpyGen) genTypeMethods(sym *symbol) {
 			}
 			g.impl.Printf(
 				"{%[1]q, (PyCFunction)cpy_func_%[2]s, %[3]s, %[4]q},\n",
-				msym.goname,
+				g.pkg.naming.name(m.Name(), g.pkg.alias(sym.goname, m), false),
 				msym.id,
 				margs,
 				msym.doc,
 			)
 		}
 	}
+	if sym.isBasic() && isComplexType(sym.GoType()) {
+		g.impl.Printf(
+			"{%[1]q, (PyCFunction)cpy_func_%[2]s_complex, METH_NOARGS, %[3]q},\n",
+			"__complex__",
+			sym.id,
+			"returns the value as a python complex",
+		)
+	}
 	g.impl.Printf("{NULL} /* sentinel */\n")
 	g.impl.Outdent()
 	g.impl.Printf("};\n\n")
@@ -427,6 +530,9 @@ func (g *cpyGen) genTypeProtocols(sym *symbol) {
 	if sym.isSignature() {
 		g.genTypeTPCall(sym)
 	}
+	if sym.isBasic() && isComplexType(sym.GoType()) {
+		g.genTypeTPAsNumber(sym)
+	}
 }
 
 func (g *cpyGen) genTypeTPStr(sym *symbol) {
@@ -586,8 +692,16 @@ func (g *cpyGen) genTypeTPAsSequence(sym *symbol) {
 		g.impl.Outdent()
 		g.impl.Printf("}\n\n")
 		g.impl.Printf("if (v == NULL) { return 0; }\n") // FIXME(sbinet): semantics?
-		g.impl.Printf("if (!%[1]s(v, &c_v)) { return -1; }\n", esym.py2c)
-		g.impl.Printf("cgo_func_%[1]s_ass_item(self->cgopy, i, c_v);\n", sym.id)
+		if esym.isWrapped() {
+			g.impl.Printf("v = cpy_func_%[1]s_from_native(v);\n", esym.id)
+			g.impl.Printf("if (v == NULL) { return -1; }\n")
+			g.impl.Printf("if (!%[1]s(v, &c_v)) { Py_DECREF(v); return -1; }\n", esym.py2c)
+			g.impl.Printf("cgo_func_%[1]s_ass_item(self->cgopy, i, c_v);\n", sym.id)
+			g.impl.Printf("Py_DECREF(v);\n")
+		} else {
+			g.impl.Printf("if (!%[1]s(v, &c_v)) { return -1; }\n", esym.py2c)
+			g.impl.Printf("cgo_func_%[1]s_ass_item(self->cgopy, i, c_v);\n", sym.id)
+		}
 		g.impl.Printf("return 0;\n")
 		g.impl.Outdent()
 		g.impl.Printf("}\n\n")
@@ -617,8 +731,16 @@ func (g *cpyGen) genTypeTPAsSequence(sym *symbol) {
 			g.impl.Printf("%[1]s c_v;\n", esym.cgoname)
 			g.impl.Printf("GoSlice *slice = (GoSlice*)(self->cgopy);\n")
 			g.impl.Printf("if (v == NULL) { return 0; }\n") // FIXME(sbinet): semantics?
-			g.impl.Printf("if (!%[1]s(v, &c_v)) { return -1; }\n", esym.py2c)
-			g.impl.Printf("cgo_func_%[1]s_append(self->cgopy, c_v);\n", sym.id)
+			if esym.isWrapped() {
+				g.impl.Printf("v = cpy_func_%[1]s_from_native(v);\n", esym.id)
+				g.impl.Printf("if (v == NULL) { return -1; }\n")
+				g.impl.Printf("if (!%[1]s(v, &c_v)) { Py_DECREF(v); return -1; }\n", esym.py2c)
+				g.impl.Printf("cgo_func_%[1]s_append(self->cgopy, c_v);\n", sym.id)
+				g.impl.Printf("Py_DECREF(v);\n")
+			} else {
+				g.impl.Printf("if (!%[1]s(v, &c_v)) { return -1; }\n", esym.py2c)
+				g.impl.Printf("cgo_func_%[1]s_append(self->cgopy, c_v);\n", sym.id)
+			}
 			g.impl.Printf("return 0;\n")
 			g.impl.Outdent()
 			g.impl.Printf("}\n\n")
@@ -715,7 +837,7 @@ func (g *cpyGen) genTypeTPAsBuffer(sym *symbol) {
 	var esize int64
 	var arrlen int64
 	esym := g.pkg.syms.symtype(sym.GoType())
-	switch o := sym.GoType().(type) {
+	switch o := sym.GoType().Underlying().(type) {
 	case *types.Array:
 		esize = g.pkg.sz.Sizeof(o.Elem())
 		arrlen = o.Len()
@@ -897,6 +1019,170 @@ func (g *cpyGen) genTypeTPAsBuffer(sym *symbol) {
 	}
 }
 
+func (g *cpyGen) genTypeTPAsNumber(sym *symbol) {
+	g.decl.Printf("\n/* number support for %s */\n", sym.gofmt())
+
+	bsym := g.pkg.syms.symtype(sym.GoType().Underlying())
+
+	g.decl.Printf("static int\n")
+	g.decl.Printf("cpy_func_%[1]s_as_number(PyObject *o, %[2]s *v);\n",
+		sym.id,
+		sym.cgoname,
+	)
+
+	g.impl.Printf("\n/* number conversion for %s */\n", sym.gofmt())
+	g.impl.Printf("static int\n")
+	g.impl.Printf("cpy_func_%[1]s_as_number(PyObject *o, %[2]s *v) {\n",
+		sym.id,
+		sym.cgoname,
+	)
+	g.impl.Indent()
+	g.impl.Printf("if (cpy_func_%[1]s_check(o)) {\n", sym.id)
+	g.impl.Indent()
+	g.impl.Printf("*v = ((%[1]s*)o)->cgopy;\n", sym.cpyname)
+	g.impl.Printf("return 1;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n")
+	g.impl.Printf("if (!PyNumber_Check(o)) {\n")
+	g.impl.Indent()
+	g.impl.Printf("return 0;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n")
+	g.impl.Printf("return %[1]s(o, (%[2]s*)v);\n", bsym.py2c, bsym.cgoname)
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	for _, op := range []struct {
+		name string
+		op   string
+	}{
+		{"add", "+"},
+		{"subtract", "-"},
+		{"multiply", "*"},
+		{"divide", "/"},
+	} {
+		g.decl.Printf("static PyObject*\n")
+		g.decl.Printf("cpy_func_%[1]s_nb_%[2]s(PyObject *a, PyObject *b);\n",
+			sym.id,
+			op.name,
+		)
+
+		g.impl.Printf("\n/* nb_%[1]s */\n", op.name)
+		g.impl.Printf("static PyObject*\n")
+		g.impl.Printf("cpy_func_%[1]s_nb_%[2]s(PyObject *a, PyObject *b) {\n",
+			sym.id,
+			op.name,
+		)
+		g.impl.Indent()
+		g.impl.Printf("%[1]s c_a;\n%[1]s c_b;\n%[1]s c_ret;\n\n", sym.cgoname)
+		g.impl.Printf(
+			"if (!cpy_func_%[1]s_as_number(a, &c_a) || !cpy_func_%[1]s_as_number(b, &c_b)) {\n",
+			sym.id,
+		)
+		g.impl.Indent()
+		g.impl.Printf("PyErr_Clear();\n")
+		g.impl.Printf("Py_INCREF(Py_NotImplemented);\n")
+		g.impl.Printf("return Py_NotImplemented;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+		if op.op == "/" {
+			g.impl.Printf("if (c_b == 0) {\n")
+			g.impl.Indent()
+			g.impl.Printf("PyErr_SetString(PyExc_ZeroDivisionError, ")
+			g.impl.Printf("\"complex division by zero\");\n")
+			g.impl.Printf("return NULL;\n")
+			g.impl.Outdent()
+			g.impl.Printf("}\n\n")
+		}
+		g.impl.Printf("c_ret = c_a %s c_b;\n", op.op)
+		g.impl.Printf("return cgopy_cnv_c2py_%[1]s(&c_ret);\n", sym.id)
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+	}
+
+	for _, op := range []struct {
+		name string
+		op   string
+	}{
+		{"negative", "-"},
+		{"positive", "+"},
+	} {
+		g.decl.Printf("static PyObject*\n")
+		g.decl.Printf("cpy_func_%[1]s_nb_%[2]s(%[3]s *self);\n",
+			sym.id,
+			op.name,
+			sym.cpyname,
+		)
+
+		g.impl.Printf("\n/* nb_%[1]s */\n", op.name)
+		g.impl.Printf("static PyObject*\n")
+		g.impl.Printf("cpy_func_%[1]s_nb_%[2]s(%[3]s *self) {\n",
+			sym.id,
+			op.name,
+			sym.cpyname,
+		)
+		g.impl.Indent()
+		g.impl.Printf("%[1]s c_ret = %[2]sself->cgopy;\n", sym.cgoname, op.op)
+		g.impl.Printf("return cgopy_cnv_c2py_%[1]s(&c_ret);\n", sym.id)
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+	}
+
+	g.decl.Printf("static PyObject*\n")
+	g.decl.Printf("cpy_func_%[1]s_nb_absolute(%[2]s *self);\n",
+		sym.id,
+		sym.cpyname,
+	)
+
+	g.impl.Printf("\n/* nb_absolute */\n")
+	g.impl.Printf("static PyObject*\n")
+	g.impl.Printf("cpy_func_%[1]s_nb_absolute(%[2]s *self) {\n",
+		sym.id,
+		sym.cpyname,
+	)
+	g.impl.Indent()
+	g.impl.Printf("return PyFloat_FromDouble(cabs(self->cgopy));\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	g.decl.Printf("static int\n")
+	g.decl.Printf("cpy_func_%[1]s_nb_nonzero(%[2]s *self);\n",
+		sym.id,
+		sym.cpyname,
+	)
+
+	g.impl.Printf("\n/* nb_nonzero */\n")
+	g.impl.Printf("static int\n")
+	g.impl.Printf("cpy_func_%[1]s_nb_nonzero(%[2]s *self) {\n",
+		sym.id,
+		sym.cpyname,
+	)
+	g.impl.Indent()
+	g.impl.Printf("return self->cgopy != 0;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	g.impl.Printf("\n/* tp_as_number */\n")
+	g.impl.Printf("static PyNumberMethods %[1]s_tp_as_number = {\n", sym.cpyname)
+	g.impl.Indent()
+	g.impl.Printf(".nb_add = (binaryfunc)cpy_func_%[1]s_nb_add,\n", sym.id)
+	g.impl.Printf(".nb_subtract = (binaryfunc)cpy_func_%[1]s_nb_subtract,\n", sym.id)
+	g.impl.Printf(".nb_multiply = (binaryfunc)cpy_func_%[1]s_nb_multiply,\n", sym.id)
+	switch g.lang {
+	case 2:
+		g.impl.Printf(".nb_divide = (binaryfunc)cpy_func_%[1]s_nb_divide,\n", sym.id)
+		g.impl.Printf(".nb_nonzero = (inquiry)cpy_func_%[1]s_nb_nonzero,\n", sym.id)
+	case 3:
+		g.impl.Printf(".nb_bool = (inquiry)cpy_func_%[1]s_nb_nonzero,\n", sym.id)
+	}
+	g.impl.Printf(".nb_true_divide = (binaryfunc)cpy_func_%[1]s_nb_divide,\n", sym.id)
+	g.impl.Printf(".nb_negative = (unaryfunc)cpy_func_%[1]s_nb_negative,\n", sym.id)
+	g.impl.Printf(".nb_positive = (unaryfunc)cpy_func_%[1]s_nb_positive,\n", sym.id)
+	g.impl.Printf(".nb_absolute = (unaryfunc)cpy_func_%[1]s_nb_absolute,\n", sym.id)
+	g.impl.Outdent()
+	g.impl.Printf("};\n\n")
+}
+
 func (g *cpyGen) genTypeTPCall(sym *symbol) {
 
 	if !sym.isSignature() {
@@ -1051,6 +1337,10 @@ func (g *cpyGen) genTypeConverter(sym *symbol) {
 		sym.id,
 		sym.cgoname,
 	)
+	if _, ok := g.pkg.enum(sym); ok {
+		g.decl.Printf("/* enum class for %s (NULL until the module is initialized) */\n", sym.gofmt())
+		g.decl.Printf("static PyObject *cpy_enum_%s = NULL;\n\n", sym.id)
+	}
 
 	g.impl.Printf("static int\n")
 	g.impl.Printf("cgopy_cnv_py2c_%[1]s(PyObject *o, %[2]s *addr) {\n",
@@ -1070,6 +1360,15 @@ func (g *cpyGen) genTypeConverter(sym *symbol) {
 		g.impl.Printf("GoInterface *iface = (GoInterface*)(addr);\n")
 		g.impl.Printf("*iface = ((gopy_object*)o)->eface((gopy_object*)o);\n")
 		g.impl.Printf("return 1;\n")
+	} else if bsym := g.pkg.syms.symtype(sym.GoType().Underlying()); sym.isBasic() && bsym.py2c != "" {
+		g.impl.Printf("if (cpy_func_%s_check(o)) {\n", sym.id)
+		g.impl.Indent()
+		g.impl.Printf("self = (%s *)o;\n", sym.cpyname)
+		g.impl.Printf("*addr = self->cgopy;\n")
+		g.impl.Printf("return 1;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+		g.impl.Printf("return %[1]s(o, (%[2]s*)addr);\n", bsym.py2c, bsym.cgoname)
 	} else {
 		g.impl.Printf("self = (%s *)o;\n", sym.cpyname)
 		g.impl.Printf("*addr = self->cgopy;\n")
@@ -1081,6 +1380,30 @@ func (g *cpyGen) genTypeConverter(sym *symbol) {
 	g.impl.Printf("static PyObject*\n")
 	g.impl.Printf("cgopy_cnv_c2py_%[1]s(%[2]s *addr) {\n", sym.id, sym.cgoname)
 	g.impl.Indent()
+	if _, ok := g.pkg.enum(sym); ok {
+		bsym := g.pkg.syms.symtype(sym.GoType().Underlying())
+		g.impl.Printf("if (cpy_enum_%s != NULL) {\n", sym.id)
+		g.impl.Indent()
+		g.impl.Printf("PyObject *v = %[1]s((%[2]s*)addr);\n", bsym.c2py, bsym.cgoname)
+		g.impl.Printf("PyObject *m = NULL;\n")
+		g.impl.Printf("if (v == NULL) {\n")
+		g.impl.Indent()
+		g.impl.Printf("return NULL;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+		g.impl.Printf("m = PyObject_CallFunctionObjArgs(cpy_enum_%s, v, NULL);\n", sym.id)
+		g.impl.Printf("if (m == NULL && PyErr_ExceptionMatches(PyExc_ValueError)) {\n")
+		g.impl.Indent()
+		g.impl.Printf("/* not a member of the enum: return the plain value. */\n")
+		g.impl.Printf("PyErr_Clear();\n")
+		g.impl.Printf("return v;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+		g.impl.Printf("Py_DECREF(v);\n")
+		g.impl.Printf("return m;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+	}
 	g.impl.Printf("PyObject *o = cpy_func_%[1]s_new(&%[2]sType, 0, 0);\n",
 		sym.id,
 		sym.cpyname,
@@ -1126,3 +1449,175 @@ func (g *cpyGen) genTypeTypeCheck(sym *symbol) {
 	g.impl.Printf("}\n\n")
 
 }
+
+// genTypeNative generates the functions converting values of type sym from
+// and to native python values (dicts, lists, ...)
+func (g *cpyGen) genTypeNative(sym *symbol) {
+	g.decl.Printf("\n/* native python values support for %s */\n", sym.gofmt())
+	g.decl.Printf("static PyObject*\ncpy_func_%[1]s_to_native(PyObject *self);\n", sym.id)
+	g.decl.Printf("static PyObject*\ncpy_func_%[1]s_from_native(PyObject *o);\n", sym.id)
+
+	g.impl.Printf("\n/* conversion of %s to a native python value */\n", sym.gofmt())
+	g.impl.Printf("static PyObject*\ncpy_func_%[1]s_to_native(PyObject *self) {\n", sym.id)
+	g.impl.Indent()
+	switch {
+	case sym.isStruct():
+		g.impl.Printf("return cpy_func_%[1]s_to_dict((%[2]s*)self, NULL);\n",
+			sym.id,
+			sym.cpyname,
+		)
+
+	case sym.isBasic():
+		bsym := g.pkg.syms.symtype(sym.GoType().Underlying())
+		if bsym.c2py == "" {
+			g.impl.Printf("Py_INCREF(self);\n")
+			g.impl.Printf("return self;\n")
+			break
+		}
+		g.impl.Printf("return %[1]s((%[2]s*)&((%[3]s*)self)->cgopy);\n",
+			bsym.c2py,
+			bsym.cgoname,
+			sym.cpyname,
+		)
+
+	case sym.isArray() || sym.isSlice():
+		var esym *symbol
+		switch typ := sym.GoType().Underlying().(type) {
+		case *types.Array:
+			esym = g.pkg.syms.symtype(typ.Elem())
+		case *types.Slice:
+			esym = g.pkg.syms.symtype(typ.Elem())
+		}
+		g.impl.Printf("Py_ssize_t i = 0;\n")
+		g.impl.Printf("Py_ssize_t len = cpy_func_%[1]s_len((%[2]s*)self);\n",
+			sym.id,
+			sym.cpyname,
+		)
+		g.impl.Printf("PyObject *list = PyList_New(len);\n")
+		g.impl.Printf("if (list == NULL) {\n")
+		g.impl.Indent()
+		g.impl.Printf("return NULL;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+		g.impl.Printf("for (i = 0; i < len; i++) {\n")
+		g.impl.Indent()
+		g.impl.Printf("PyObject *item = cpy_func_%[1]s_item((%[2]s*)self, i);\n",
+			sym.id,
+			sym.cpyname,
+		)
+		if esym.isWrapped() {
+			g.impl.Printf("if (item != NULL) {\n")
+			g.impl.Indent()
+			g.impl.Printf("PyObject *v = cpy_func_%[1]s_to_native(item);\n", esym.id)
+			g.impl.Printf("Py_DECREF(item);\n")
+			g.impl.Printf("item = v;\n")
+			g.impl.Outdent()
+			g.impl.Printf("}\n")
+		}
+		g.impl.Printf("if (item == NULL) {\n")
+		g.impl.Indent()
+		g.impl.Printf("Py_DECREF(list);\n")
+		g.impl.Printf("return NULL;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+		g.impl.Printf("PyList_SET_ITEM(list, i, item);\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+		g.impl.Printf("return list;\n")
+
+	case sym.isMap():
+		ksym, esym := g.pkg.syms.mapsyms(sym)
+		if ksym == nil {
+			g.impl.Printf("Py_INCREF(self);\n")
+			g.impl.Printf("return self;\n")
+			break
+		}
+		bsym := g.pkg.syms.symtype(ksym.GoType().Underlying())
+		g.impl.Printf("%[1]s *m = (%[1]s*)self;\n", sym.cpyname)
+		g.impl.Printf("PyObject *dict = PyDict_New();\n")
+		g.impl.Printf("if (dict == NULL) {\n")
+		g.impl.Indent()
+		g.impl.Printf("return NULL;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+		g.impl.Printf("GoSlice *keys = (GoSlice*)cgo_func_%[1]s_keys(m->cgopy);\n", sym.id)
+		g.impl.Printf("%[1]s *data = (%[1]s*)(keys->data);\n", ksym.cgoname)
+		g.impl.Printf("Py_ssize_t i = 0;\n")
+		g.impl.Printf("for (i = 0; i < keys->len; i++) {\n")
+		g.impl.Indent()
+		g.impl.Printf("%[1]s c_v = cgo_func_%[2]s_get(m->cgopy, data[i]);\n",
+			esym.cgoname,
+			sym.id,
+		)
+		g.impl.Printf("PyObject *k = %[1]s((%[2]s*)&data[i]);\n", bsym.c2py, bsym.cgoname)
+		g.impl.Printf("PyObject *v = %[1]s(&c_v);\n", esym.c2py)
+		if esym.isWrapped() {
+			g.impl.Printf("if (v != NULL) {\n")
+			g.impl.Indent()
+			g.impl.Printf("PyObject *tmp = cpy_func_%[1]s_to_native(v);\n", esym.id)
+			g.impl.Printf("Py_DECREF(v);\n")
+			g.impl.Printf("v = tmp;\n")
+			g.impl.Outdent()
+			g.impl.Printf("}\n")
+		}
+		g.impl.Printf("if (k == NULL || v == NULL || PyDict_SetItem(dict, k, v) < 0) {\n")
+		g.impl.Indent()
+		g.impl.Printf("Py_XDECREF(k);\n")
+		g.impl.Printf("Py_XDECREF(v);\n")
+		g.impl.Printf("Py_CLEAR(dict);\n")
+		g.impl.Printf("break;\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+		g.impl.Printf("Py_DECREF(k);\n")
+		g.impl.Printf("Py_DECREF(v);\n")
+		g.impl.Outdent()
+		g.impl.Printf("}\n")
+		g.impl.Printf("cgopy_decref((void*)keys);\n")
+		g.impl.Printf("return dict;\n")
+
+	default:
+		// interfaces and funcs have no native python equivalent.
+		g.impl.Printf("Py_INCREF(self);\n")
+		g.impl.Printf("return self;\n")
+	}
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+
+	g.impl.Printf("\n/* conversion of a native python value to %s */\n", sym.gofmt())
+	g.impl.Printf("static PyObject*\ncpy_func_%[1]s_from_native(PyObject *o) {\n", sym.id)
+	g.impl.Indent()
+	g.impl.Printf("if (o == NULL || cpy_func_%[1]s_check(o)) {\n", sym.id)
+	g.impl.Indent()
+	g.impl.Printf("Py_XINCREF(o);\n")
+	g.impl.Printf("return o;\n")
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+	switch {
+	case sym.isStruct():
+		g.impl.Printf("if (PyDict_Check(o)) {\n")
+		g.impl.Indent()
+		g.impl.Printf("return cpy_func_%[1]s_new_from_dict(&%[2]sType, o);\n",
+			sym.id,
+			sym.cpyname,
+		)
+		g.impl.Outdent()
+		g.impl.Printf("}\n\n")
+		g.impl.Printf(
+			"PyErr_Format(PyExc_TypeError, \"expected a dict or %[1]s, got %%s\", Py_TYPE(o)->tp_name);\n",
+			sym.goname,
+		)
+		g.impl.Printf("return NULL;\n")
+
+	case sym.isInterface() || sym.isSignature():
+		g.impl.Printf("Py_INCREF(o);\n")
+		g.impl.Printf("return o;\n")
+
+	default:
+		g.impl.Printf(
+			"return PyObject_CallFunctionObjArgs((PyObject*)&%[1]sType, o, NULL);\n",
+			sym.cpyname,
+		)
+	}
+	g.impl.Outdent()
+	g.impl.Printf("}\n\n")
+}
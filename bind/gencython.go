@@ -0,0 +1,401 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"strings"
+)
+
+const (
+	cythonPreamble = `# Package %[1]s declares the cgo functions and the python object types of the
+# gopy bindings of the Go package %[2]s, for Cython modules.
+# gopy gen -lang=cython %[2]s
+#
+# File is generated by gopy gen. Do not edit.
+#
+# The declarations refer to the %[1]s.h header written by gopy gen -lang=go,
+# and the functions are defined by the %[1]s extension module built by gopy
+# bind: import %[1]s before calling them.
+
+from libc.stddef cimport ptrdiff_t
+
+cdef extern from "%[1]s.h":
+    ctypedef signed char GoInt8
+    ctypedef unsigned char GoUint8
+    ctypedef short GoInt16
+    ctypedef unsigned short GoUint16
+    ctypedef int GoInt32
+    ctypedef unsigned int GoUint32
+    ctypedef long long GoInt64
+    ctypedef unsigned long long GoUint64
+    ctypedef GoInt64 GoInt
+    ctypedef GoUint64 GoUint
+    ctypedef size_t GoUintptr
+    ctypedef float GoFloat32
+    ctypedef double GoFloat64
+    ctypedef float complex GoComplex64
+    ctypedef double complex GoComplex128
+
+    ctypedef struct GoString:
+        const char* p
+        ptrdiff_t n
+
+    ctypedef void* GoMap
+    ctypedef void* GoChan
+
+    ctypedef struct GoInterface:
+        void* t
+        void* v
+
+    ctypedef struct GoSlice:
+        void* data
+        GoInt len
+        GoInt cap
+
+# the python objects wrapping Go values, as laid out by the %[1]s extension
+# module.
+cdef extern from *:
+    """
+    #ifndef GOPY_OBJECT_MODEL
+    #define GOPY_OBJECT_MODEL
+    struct _gopy_object;
+    typedef GoInterface (*gopy_efacefunc)(struct _gopy_object *);
+    typedef struct _gopy_object {
+        PyObject_HEAD
+        void *go;
+        gopy_efacefunc eface;
+    } gopy_object;
+    #endif
+%[3]s    """
+
+    # gopy_efacefunc returns the Go value of a gopy_object as an empty
+    # interface.
+    ctypedef GoInterface (*gopy_efacefunc)(void*)
+
+    # gopy_object is the layout shared by all the python objects wrapping a
+    # Go value.
+    ctypedef struct gopy_object:
+        void* go
+        gopy_efacefunc eface
+%[4]s
+cdef extern from "%[1]s.h":
+%[5]s`
+)
+
+// cyKeywords lists the Cython keywords and C type names, which can not be
+// used as parameter names on top of the python keywords.
+var cyKeywords = map[string]bool{
+	"api": true, "bint": true, "char": true, "cdef": true, "cimport": true,
+	"complex": true, "const": true, "cpdef": true, "ctypedef": true,
+	"double": true, "enum": true, "extern": true, "float": true, "gil": true,
+	"include": true, "inline": true, "int": true, "long": true, "new": true,
+	"nogil": true, "object": true, "public": true, "readonly": true,
+	"short": true, "signed": true, "sizeof": true, "struct": true,
+	"union": true, "unsigned": true, "void": true, "volatile": true,
+	"DEF": true, "ELIF": true, "ELSE": true, "IF": true, "NULL": true,
+}
+
+// cyName returns a Cython identifier for the Go identifier name.
+func cyName(name string) string {
+	if cyKeywords[name] {
+		return name + "_"
+	}
+	return pyName(name)
+}
+
+// GenCython generates a Cython .pxd file declaring the functions exported
+// by the cgo package of GenGo and the object types of the extension module of
+// GenCPython for pkg.
+func GenCython(w io.Writer, fset *token.FileSet, pkg *Package) error {
+	// the python configuration does not change the exported functions.
+	src := new(bytes.Buffer)
+	err := GenGo(src, fset, pkg, 2, &PyConfig{Version: 2})
+	if err != nil {
+		return err
+	}
+
+	g := &cythonGen{
+		pkg:     pkg,
+		objs:    new(bytes.Buffer),
+		decls:   new(bytes.Buffer),
+		funcs:   new(bytes.Buffer),
+		cgotyps: make(map[string]bool),
+	}
+	g.genTypes()
+	err = g.genFuncs(src.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, cythonPreamble,
+		pkg.Name(), pkg.ImportPath(),
+		g.objs.String(), g.decls.String(), g.funcs.String(),
+	)
+	return err
+}
+
+// cythonGen generates the Cython declarations of a package.
+type cythonGen struct {
+	pkg *Package
+
+	objs  *bytes.Buffer // C definitions of the object types
+	decls *bytes.Buffer // Cython declarations of the object types
+	funcs *bytes.Buffer // Cython declarations of the cgo functions
+
+	cgotyps map[string]bool // names of the declared cgo_type_xxx types
+}
+
+// genTypes declares the object types, in the order of GenCPython.
+func (g *cythonGen) genTypes() {
+	for _, n := range g.pkg.syms.names() {
+		sym := g.pkg.syms.sym(n)
+		if !sym.isType() || sym.isStruct() {
+			continue
+		}
+		if sym.isBasic() && !sym.isNamed() {
+			continue
+		}
+		ctyp := "void*"
+		if sym.isBasic() {
+			ctyp = g.pkg.syms.symtype(sym.GoType().Underlying()).cgoname
+		}
+		g.genType(sym, ctyp)
+	}
+
+	for _, s := range g.pkg.structs {
+		g.genType(s.sym, "void*")
+	}
+}
+
+// genType declares the object type of sym, holding a value of C type ctyp.
+func (g *cythonGen) genType(sym *symbol, ctyp string) {
+	g.cgotyps[sym.cgoname] = true
+
+	fmt.Fprintf(g.objs, "\n    typedef %s %s;\n", ctyp, sym.cgoname)
+	fmt.Fprintf(g.objs, "    typedef struct {\n")
+	fmt.Fprintf(g.objs, "        PyObject_HEAD\n")
+	fmt.Fprintf(g.objs, "        %s cgopy;\n", sym.cgoname)
+	fmt.Fprintf(g.objs, "        gopy_efacefunc eface;\n")
+	fmt.Fprintf(g.objs, "    } %s;\n", sym.cpyname)
+
+	fmt.Fprintf(g.decls, "\n    # %s is the python object wrapping values of type %s.\n", sym.cpyname, sym.gofmt())
+	fmt.Fprintf(g.decls, "    ctypedef %s %s\n", ctyp, sym.cgoname)
+	fmt.Fprintf(g.decls, "    ctypedef struct %s:\n", sym.cpyname)
+	fmt.Fprintf(g.decls, "        %s cgopy\n", sym.cgoname)
+	fmt.Fprintf(g.decls, "        gopy_efacefunc eface\n")
+}
+
+// genFuncs declares the functions exported by the cgo package in src.
+func (g *cythonGen) genFuncs(src []byte) error {
+	exports, err := cgoExports(src, g.cgotyps)
+	if err != nil {
+		return err
+	}
+
+	for i, fct := range exports {
+		if i > 0 {
+			g.funcs.WriteString("\n")
+		}
+		ret := "void"
+		switch len(fct.results) {
+		case 0:
+		case 1:
+			ret = fct.results[0].ctyp
+		default:
+			ret = fct.name + "_return"
+			fmt.Fprintf(g.funcs, "    struct %s:\n", ret)
+			for _, r := range fct.results {
+				name := cyName(r.name)
+				if name != r.name {
+					name = fmt.Sprintf("%s %q", name, r.name)
+				}
+				fmt.Fprintf(g.funcs, "        %s %s\n", r.ctyp, name)
+			}
+			g.funcs.WriteString("\n")
+		}
+
+		params := make([]string, len(fct.params))
+		for i, p := range fct.params {
+			params[i] = p.ctyp + " " + cyName(p.name)
+		}
+		fmt.Fprintf(g.funcs, "    %s %s(%s)\n", ret, fct.name, strings.Join(params, ", "))
+	}
+	return nil
+}
+
+// cgoExport describes a function exported to C by a cgo package.
+type cgoExport struct {
+	name    string
+	params  []cgoValue
+	results []cgoValue
+}
+
+// cgoValue is a parameter or a result of an exported function.
+type cgoValue struct {
+	name string
+	ctyp string // C type of the value
+}
+
+// cgoExports returns the functions exported by the cgo package in src, with
+// the C types cgo gives them in its export header.
+// The named types of the package listed in keep keep their name rather than
+// being replaced by their C type.
+func cgoExports(src []byte, keep map[string]bool) ([]cgoExport, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("gopy: could not parse cgo package: %v", err)
+	}
+
+	typedefs := make(map[string]ast.Expr)
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.TypeSpec)
+			typedefs[spec.Name.Name] = spec.Type
+		}
+	}
+
+	var ctype func(expr ast.Expr) (string, error)
+	ctype = func(expr ast.Expr) (string, error) {
+		switch expr := expr.(type) {
+		case *ast.Ident:
+			if ctyp, ok := cgoBasicTypes[expr.Name]; ok {
+				return ctyp, nil
+			}
+			if keep[expr.Name] {
+				return expr.Name, nil
+			}
+			if typ, ok := typedefs[expr.Name]; ok {
+				return ctype(typ)
+			}
+		case *ast.SelectorExpr:
+			x, ok := expr.X.(*ast.Ident)
+			switch {
+			case !ok:
+			case x.Name == "unsafe" && expr.Sel.Name == "Pointer":
+				return "void*", nil
+			case x.Name == "C":
+				if ctyp, ok := cgoCTypes[expr.Sel.Name]; ok {
+					return ctyp, nil
+				}
+				return expr.Sel.Name, nil
+			}
+		case *ast.StarExpr:
+			elem, err := ctype(expr.X)
+			if err != nil {
+				return "", err
+			}
+			return elem + "*", nil
+		case *ast.ArrayType:
+			if expr.Len == nil {
+				return "GoSlice", nil
+			}
+		case *ast.MapType:
+			return "GoMap", nil
+		case *ast.ChanType:
+			return "GoChan", nil
+		case *ast.InterfaceType:
+			return "GoInterface", nil
+		case *ast.FuncType:
+			return "void*", nil
+		}
+		var buf bytes.Buffer
+		ast.Fprint(&buf, fset, expr, nil)
+		return "", fmt.Errorf("gopy: unsupported type in exported function: %s", buf.String())
+	}
+
+	values := func(fields *ast.FieldList, prefix string) ([]cgoValue, error) {
+		var vs []cgoValue
+		if fields == nil {
+			return vs, nil
+		}
+		for _, field := range fields.List {
+			ctyp, err := ctype(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			if len(field.Names) == 0 {
+				vs = append(vs, cgoValue{fmt.Sprintf("%s%d", prefix, len(vs)), ctyp})
+			}
+			for _, name := range field.Names {
+				vs = append(vs, cgoValue{name.Name, ctyp})
+			}
+		}
+		return vs, nil
+	}
+
+	var exports []cgoExport
+	for _, decl := range f.Decls {
+		fct, ok := decl.(*ast.FuncDecl)
+		if !ok || fct.Doc == nil || fct.Recv != nil {
+			continue
+		}
+		exported := false
+		for _, c := range fct.Doc.List {
+			if strings.TrimSpace(strings.TrimPrefix(c.Text, "//export ")) == fct.Name.Name {
+				exported = true
+			}
+		}
+		if !exported {
+			continue
+		}
+		params, err := values(fct.Type.Params, "p")
+		if err != nil {
+			return nil, err
+		}
+		results, err := values(fct.Type.Results, "r")
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, cgoExport{fct.Name.Name, params, results})
+	}
+	return exports, nil
+}
+
+// cgoBasicTypes maps the predeclared Go types to the C types of the cgo
+// export header.
+var cgoBasicTypes = map[string]string{
+	"bool":       "GoUint8",
+	"byte":       "GoUint8",
+	"complex64":  "GoComplex64",
+	"complex128": "GoComplex128",
+	"error":      "GoInterface",
+	"float32":    "GoFloat32",
+	"float64":    "GoFloat64",
+	"int":        "GoInt",
+	"int8":       "GoInt8",
+	"int16":      "GoInt16",
+	"int32":      "GoInt32",
+	"int64":      "GoInt64",
+	"rune":       "GoInt32",
+	"string":     "GoString",
+	"uint":       "GoUint",
+	"uint8":      "GoUint8",
+	"uint16":     "GoUint16",
+	"uint32":     "GoUint32",
+	"uint64":     "GoUint64",
+	"uintptr":    "GoUintptr",
+}
+
+// cgoCTypes maps the cgo names of the C numeric types to their C spelling.
+var cgoCTypes = map[string]string{
+	"schar":     "signed char",
+	"uchar":     "unsigned char",
+	"ushort":    "unsigned short",
+	"uint":      "unsigned int",
+	"ulong":     "unsigned long",
+	"longlong":  "long long",
+	"ulonglong": "unsigned long long",
+}
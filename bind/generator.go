@@ -0,0 +1,220 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Generator generates the bindings of a Go package for a target language.
+type Generator interface {
+	// Doc returns a one-line description of the generated bindings.
+	Doc() string
+
+	// Ext returns the file name extension (e.g. ".c") of the generated
+	// bindings.
+	Ext() string
+
+	// Generate writes to w the bindings of pkg.
+	// cfg describes the python the bindings are built against, or is nil
+	// when the python is found by pkg-config.
+	Generate(w io.Writer, fset *token.FileSet, pkg *Package, cfg *PyConfig) error
+}
+
+// Binder is implemented by the generators whose bindings can be built by
+// gopy bind. The bindings of the other generators can only be generated.
+type Binder interface {
+	Generator
+
+	// BindPlan returns how the bindings of pkg are built.
+	// ext is the file name suffix of the python extension modules (e.g.
+	// ".so").
+	BindPlan(pkg *Package, ext string) BindPlan
+}
+
+// BindPlan describes how gopy bind builds the bindings of a package.
+type BindPlan struct {
+	// Lib is the file name of the shared library built from the cgo
+	// package written by the Gen generators.
+	Lib string
+	Gen []string
+
+	// Extra lists the generators of the files written next to the shared
+	// library, e.g. a pure-python module loading it.
+	Extra []string
+
+	// Files lists the names of the other files of the bindings: those
+	// written by the Extra generators, and by the build of the shared
+	// library (e.g. its C header).
+	Files []string
+}
+
+var generators struct {
+	sync.RWMutex
+	m map[string]Generator
+}
+
+// RegisterGenerator makes a generator available under the given name (as
+// passed to the -lang flag of gopy).
+// RegisterGenerator panics if a generator is already registered under name.
+func RegisterGenerator(name string, g Generator) {
+	generators.Lock()
+	defer generators.Unlock()
+	if g == nil {
+		panic("bind: nil generator")
+	}
+	if _, dup := generators.m[name]; dup {
+		panic(fmt.Errorf("bind: generator %q registered twice", name))
+	}
+	if generators.m == nil {
+		generators.m = make(map[string]Generator)
+	}
+	generators.m[name] = g
+}
+
+// LookupGenerator returns the generator registered under name.
+func LookupGenerator(name string) (Generator, bool) {
+	generators.RLock()
+	defer generators.RUnlock()
+	g, ok := generators.m[name]
+	return g, ok
+}
+
+// Generators returns the sorted names of the registered generators.
+func Generators() []string {
+	generators.RLock()
+	defer generators.RUnlock()
+	names := make([]string, 0, len(generators.m))
+	for name := range generators.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterGenerator("py2", cpyGenerator{})
+	RegisterGenerator("go", goGenerator{})
+	RegisterGenerator("c", cGenerator{})
+	RegisterGenerator("cffi", cffiGenerator{})
+	RegisterGenerator("cython", cythonGenerator{})
+	RegisterGenerator("py2low", cpyLowGenerator{})
+	RegisterGenerator("shim", shimGenerator{})
+}
+
+// cpyGenerator generates the CPython-2 extension module.
+type cpyGenerator struct{}
+
+func (cpyGenerator) Doc() string { return "CPython-2 C extension module" }
+func (cpyGenerator) Ext() string { return ".c" }
+
+func (cpyGenerator) Generate(w io.Writer, fset *token.FileSet, pkg *Package, cfg *PyConfig) error {
+	return GenCPython(w, fset, pkg, 2)
+}
+
+func (cpyGenerator) BindPlan(pkg *Package, ext string) BindPlan {
+	return BindPlan{
+		Lib: pkg.Name() + ext,
+		Gen: []string{"py2", "go"},
+	}
+}
+
+// cpyLowGenerator generates the CPython-2 extension module _<pkg>, wrapped
+// by the python layer of shimGenerator.
+type cpyLowGenerator struct{}
+
+func (cpyLowGenerator) Doc() string { return "CPython-2 C extension module _<pkg> for the shim" }
+func (cpyLowGenerator) Ext() string { return ".c" }
+
+func (cpyLowGenerator) Generate(w io.Writer, fset *token.FileSet, pkg *Package, cfg *PyConfig) error {
+	return GenCPythonModule(w, fset, pkg, 2, "_"+pkg.Name())
+}
+
+// goGenerator generates the cgo package exporting the Go package to C.
+type goGenerator struct{}
+
+func (goGenerator) Doc() string { return "cgo package exporting the Go package to C" }
+func (goGenerator) Ext() string { return ".go" }
+
+func (goGenerator) Generate(w io.Writer, fset *token.FileSet, pkg *Package, cfg *PyConfig) error {
+	lang := 2
+	if cfg != nil {
+		lang = cfg.Version
+	}
+	return GenGo(w, fset, pkg, lang, cfg)
+}
+
+// cGenerator generates the cgo package exposing the Go package through a
+// plain C API.
+type cGenerator struct{}
+
+func (cGenerator) Doc() string { return "cgo package exposing the Go package through a C API" }
+func (cGenerator) Ext() string { return "_capi.go" }
+
+func (cGenerator) Generate(w io.Writer, fset *token.FileSet, pkg *Package, cfg *PyConfig) error {
+	return GenC(w, fset, pkg)
+}
+
+func (cGenerator) BindPlan(pkg *Package, ext string) BindPlan {
+	return BindPlan{
+		Lib:   "lib" + pkg.Name() + ".so",
+		Gen:   []string{"c"},
+		Files: []string{"lib" + pkg.Name() + ".h"},
+	}
+}
+
+// cffiGenerator generates the pure-python module calling the C API with cffi.
+type cffiGenerator struct{}
+
+func (cffiGenerator) Doc() string { return "pure-python module calling the C API with cffi" }
+func (cffiGenerator) Ext() string { return ".py" }
+
+func (cffiGenerator) Generate(w io.Writer, fset *token.FileSet, pkg *Package, cfg *PyConfig) error {
+	return GenCFFI(w, fset, pkg)
+}
+
+func (cffiGenerator) BindPlan(pkg *Package, ext string) BindPlan {
+	return BindPlan{
+		Lib:   "lib" + pkg.Name() + ".so",
+		Gen:   []string{"c"},
+		Extra: []string{"cffi"},
+		Files: []string{pkg.Name() + ".py"},
+	}
+}
+
+// cythonGenerator generates the Cython declarations of the cgo package and of
+// the extension module.
+type cythonGenerator struct{}
+
+func (cythonGenerator) Doc() string { return "Cython declarations of the cgo functions" }
+func (cythonGenerator) Ext() string { return ".pxd" }
+
+func (cythonGenerator) Generate(w io.Writer, fset *token.FileSet, pkg *Package, cfg *PyConfig) error {
+	return GenCython(w, fset, pkg)
+}
+
+// shimGenerator generates the python layer over the extension module of
+// cpyLowGenerator.
+type shimGenerator struct{}
+
+func (shimGenerator) Doc() string { return "pure-python layer over the py2low extension module" }
+func (shimGenerator) Ext() string { return ".py" }
+
+func (shimGenerator) Generate(w io.Writer, fset *token.FileSet, pkg *Package, cfg *PyConfig) error {
+	return GenShim(w, fset, pkg)
+}
+
+func (shimGenerator) BindPlan(pkg *Package, ext string) BindPlan {
+	return BindPlan{
+		Lib:   "_" + pkg.Name() + ext,
+		Gen:   []string{"py2low", "go"},
+		Extra: []string{"shim"},
+		Files: []string{pkg.Name() + ".py"},
+	}
+}
@@ -7,9 +7,8 @@ package bind
 import (
 	"fmt"
 	"go/token"
+	"go/types"
 	"strings"
-
-	"golang.org/x/tools/go/types"
 )
 
 const (
@@ -19,7 +18,7 @@ const (
 // File is generated by gopy gen. Do not edit.
 package main
 
-//#cgo pkg-config: %[2]s --cflags --libs
+%[2]s
 //#include <stdlib.h>
 //#include <string.h>
 //#include <complex.h>
@@ -131,15 +130,19 @@ func init() {
 type goGen struct {
 	*printer
 
-	fset *token.FileSet
-	pkg  *Package
-	lang int // python's version API (2 or 3)
-	err  ErrorList
+	fset  *token.FileSet
+	pkg   *Package
+	lang  int       // python's version API (2 or 3)
+	pycfg *PyConfig // python build configuration (nil to use pkg-config)
+	err   ErrorList
 }
 
 func (g *goGen) gen() error {
 
-	g.genPreamble()
+	err := g.genPreamble()
+	if err != nil {
+		return err
+	}
 
 	// create a Cgo hook for empty packages
 	g.genPackage()
@@ -228,15 +231,34 @@ func (g *goGen) genFuncBody(f Func) {
 	}
 
 	g.Printf("%s.%s(", g.pkg.Name(), f.GoName())
+	g.genCallArgs(sig.Params())
+	g.genCallResults(results)
+}
 
-	args := sig.Params()
+// genCallArgs generates the arguments of the call of a wrapped function or
+// method, converted from their cgo types, and closes the call.
+func (g *goGen) genCallArgs(args []*Var) {
 	for i, arg := range args {
 		tail := ""
 		if i+1 < len(args) {
 			tail = ", "
 		}
 		head := arg.Name()
-		if arg.needWrap() {
+		switch {
+		case arg.isPointer():
+			typ := arg.GoType()
+			if ptr, ok := typ.(*types.Pointer); ok {
+				typ = ptr.Elem()
+			}
+			head = fmt.Sprintf(
+				"(*%s)(unsafe.Pointer(%s))",
+				types.TypeString(
+					typ,
+					func(*types.Package) string { return g.pkg.Name() },
+				),
+				arg.Name(),
+			)
+		case arg.needWrap():
 			head = fmt.Sprintf(
 				"*(*%s)(unsafe.Pointer(%s))",
 				types.TypeString(
@@ -245,20 +267,29 @@ func (g *goGen) genFuncBody(f Func) {
 				),
 				arg.Name(),
 			)
+		case arg.sym.isNamed():
+			head = fmt.Sprintf("%s(%s)", arg.sym.gofmt(), arg.Name())
 		}
 		g.Printf("%s%s", head, tail)
 	}
 	g.Printf(")\n")
+}
 
+// genCallResults generates the return statement of a wrapped function or
+// method, converting the results _gopy_NNN of the call to their cgo types.
+// Pointers to structs are passed as is: they are the handles of the values.
+func (g *goGen) genCallResults(results []*Var) {
 	if len(results) <= 0 {
 		return
 	}
 
 	for i, res := range results {
-		if !res.needWrap() {
-			continue
+		switch {
+		case res.isPointer():
+			g.Printf("cgopy_incref(unsafe.Pointer(_gopy_%03d))\n", i)
+		case res.needWrap():
+			g.Printf("cgopy_incref(unsafe.Pointer(&_gopy_%03d))\n", i)
 		}
-		g.Printf("cgopy_incref(unsafe.Pointer(&_gopy_%03d))\n", i)
 	}
 
 	g.Printf("return ")
@@ -266,15 +297,15 @@ func (g *goGen) genFuncBody(f Func) {
 		if i > 0 {
 			g.Printf(", ")
 		}
-		// if needWrap(res.GoType()) {
-		// 	g.Printf("")
-		// }
-		if res.needWrap() {
-			g.Printf("%s(unsafe.Pointer(&", res.sym.cgoname)
-		}
-		g.Printf("_gopy_%03d", i)
-		if res.needWrap() {
-			g.Printf("))")
+		switch {
+		case res.isPointer():
+			g.Printf("%s(unsafe.Pointer(_gopy_%03d))", res.sym.cgoname, i)
+		case res.needWrap():
+			g.Printf("%s(unsafe.Pointer(&_gopy_%03d))", res.sym.cgoname, i)
+		case res.sym.isNamed():
+			g.Printf("%s(_gopy_%03d)", res.sym.cgoname, i)
+		default:
+			g.Printf("_gopy_%03d", i)
 		}
 	}
 	g.Printf("\n")
@@ -290,7 +321,7 @@ func (g *goGen) genStruct(s Struct) {
 
 	for i := 0; i < typ.NumFields(); i++ {
 		f := typ.Field(i)
-		if !f.Exported() {
+		if !f.Exported() || checkType(f.Type()) != nil {
 			continue
 		}
 
@@ -319,6 +350,8 @@ func (g *goGen) genStruct(s Struct) {
 		if !fsym.isBasic() {
 			g.Printf("cgopy_incref(unsafe.Pointer(&ret.%s))\n", f.Name())
 			g.Printf("return %s(unsafe.Pointer(&ret.%s))\n", ftname, f.Name())
+		} else if fsym.isNamed() {
+			g.Printf("return %s(ret.%s)\n", ftname, f.Name())
 		} else {
 			g.Printf("return ret.%s\n", f.Name())
 		}
@@ -332,8 +365,11 @@ func (g *goGen) genStruct(s Struct) {
 		)
 		g.Indent()
 		fset := "v"
-		if !fsym.isBasic() {
+		switch {
+		case !fsym.isBasic():
 			fset = fmt.Sprintf("*(*%s)(unsafe.Pointer(v))", fsym.gofmt())
+		case fsym.isNamed():
+			fset = fmt.Sprintf("%s(v)", fsym.gofmt())
 		}
 		g.Printf(
 			"(*%[1]s)(unsafe.Pointer(self)).%[2]s = %[3]s\n",
@@ -433,42 +469,14 @@ func (g *goGen) genMethodBody(s Struct, m Func) {
 		s.sym.gofmt(),
 		m.GoName(),
 	)
-
-	args := sig.Params()
-	for i, arg := range args {
-		tail := ""
-		if i+1 < len(args) {
-			tail = ", "
-		}
-		g.Printf("%s%s", arg.Name(), tail)
-	}
-	g.Printf(")\n")
-
-	if len(results) <= 0 {
-		return
-	}
-
-	g.Printf("return ")
-	for i, res := range results {
-		if i > 0 {
-			g.Printf(", ")
-		}
-		// if needWrap(res.GoType()) {
-		// 	g.Printf("")
-		// }
-		if res.needWrap() {
-			g.Printf("%s(unsafe.Pointer(&", res.sym.cgoname)
-		}
-		g.Printf("_gopy_%03d", i)
-		if res.needWrap() {
-			g.Printf("))")
-		}
-	}
-	g.Printf("\n")
-
+	g.genCallArgs(sig.Params())
+	g.genCallResults(results)
 }
 
 func (g *goGen) genConst(o Const) {
+	if _, big := o.bigInt(); big {
+		return
+	}
 	sym := o.sym
 	g.Printf("//export cgo_func_%s_get\n", o.id)
 	g.Printf("func cgo_func_%[1]s_get() %[2]s {\n", o.id, sym.cgotypename())
@@ -692,12 +700,109 @@ func (g *goGen) genType(sym *symbol) {
 		g.Printf("}\n\n")
 	}
 
+	if e, ok := g.pkg.enum(sym); ok && e.str {
+		// names of the enum members
+		g.Printf("//export cgo_func_%[1]s_enum_name\n", sym.id)
+		g.Printf("func cgo_func_%[1]s_enum_name(self %[2]s) string {\n",
+			sym.id,
+			sym.cgoname,
+		)
+		g.Indent()
+		g.Printf("return %[1]s(self).String()\n", sym.gofmt())
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+
+	if sym.isMap() {
+		if ksym, esym := g.pkg.syms.mapsyms(sym); ksym != nil {
+			g.genTypeMap(sym, ksym, esym)
+		}
+	}
+
 	g.genTypeTPCall(sym)
 
 	g.genTypeMethods(sym)
 
 }
 
+func (g *goGen) genTypeMap(sym, ksym, esym *symbol) {
+	key := "k"
+	if ksym.isNamed() {
+		key = fmt.Sprintf("%[1]s(k)", ksym.gofmt())
+	}
+
+	// support for iterating over the keys
+	g.Printf("//export cgo_func_%[1]s_keys\n", sym.id)
+	g.Printf("func cgo_func_%[1]s_keys(self %[2]s) unsafe.Pointer {\n",
+		sym.id,
+		sym.cgoname,
+	)
+	g.Indent()
+	g.Printf("m := *(*%[1]s)(unsafe.Pointer(self))\n", sym.gofmt())
+	g.Printf("keys := make([]%[1]s, 0, len(m))\n", ksym.gofmt())
+	g.Printf("for k := range m {\n")
+	g.Indent()
+	g.Printf("keys = append(keys, k)\n")
+	g.Outdent()
+	g.Printf("}\n")
+	g.Printf("cgopy_incref(unsafe.Pointer(&keys))\n")
+	g.Printf("return unsafe.Pointer(&keys)\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	// support for __getitem__
+	g.Printf("//export cgo_func_%[1]s_get\n", sym.id)
+	g.Printf("func cgo_func_%[1]s_get(self %[2]s, k %[3]s) %[4]s {\n",
+		sym.id,
+		sym.cgoname,
+		ksym.cgotypename(),
+		esym.cgotypename(),
+	)
+	g.Indent()
+	g.Printf("m := *(*%[1]s)(unsafe.Pointer(self))\n", sym.gofmt())
+	g.Printf("elt := m[%s]\n", key)
+	if !esym.isBasic() {
+		g.Printf("cgopy_incref(unsafe.Pointer(&elt))\n")
+		g.Printf("return (%[1]s)(unsafe.Pointer(&elt))\n", esym.cgotypename())
+	} else {
+		if esym.isNamed() {
+			g.Printf("return %[1]s(elt)\n", esym.cgotypename())
+		} else {
+			g.Printf("return elt\n")
+		}
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	// support for __setitem__
+	g.Printf("//export cgo_func_%[1]s_set\n", sym.id)
+	g.Printf("func cgo_func_%[1]s_set(self %[2]s, k %[3]s, v %[4]s) {\n",
+		sym.id,
+		sym.cgoname,
+		ksym.cgotypename(),
+		esym.cgotypename(),
+	)
+	g.Indent()
+	g.Printf("m := (*%[1]s)(unsafe.Pointer(self))\n", sym.gofmt())
+	g.Printf("if *m == nil {\n")
+	g.Indent()
+	g.Printf("*m = make(%[1]s)\n", sym.gofmt())
+	g.Outdent()
+	g.Printf("}\n")
+	g.Printf("(*m)[%s] = ", key)
+	if !esym.isBasic() {
+		g.Printf("*(*%[1]s)(unsafe.Pointer(v))\n", esym.gofmt())
+	} else {
+		if esym.isNamed() {
+			g.Printf("%[1]s(v)\n", esym.gofmt())
+		} else {
+			g.Printf("v\n")
+		}
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
 func (g *goGen) genTypeTPCall(sym *symbol) {
 	if !sym.isSignature() {
 		return
@@ -820,7 +925,7 @@ func (g *goGen) genTypeMethods(sym *symbol) {
 	typ := sym.GoType().(*types.Named)
 	for imeth := 0; imeth < typ.NumMethods(); imeth++ {
 		m := typ.Method(imeth)
-		if !m.Exported() {
+		if !g.pkg.syms.isExposedMethod(m) {
 			continue
 		}
 
@@ -947,19 +1052,31 @@ func (g *goGen) genTypeMethods(sym *symbol) {
 	}
 }
 
-func (g *goGen) genPreamble() {
+func (g *goGen) genPreamble() error {
 	n := g.pkg.pkg.Name()
 	pkgimport := fmt.Sprintf("%q", g.pkg.pkg.Path())
 	if g.pkg.n == 0 {
 		pkgimport = fmt.Sprintf("_ %q", g.pkg.pkg.Path())
 	}
 
-	pkgcfg, err := getPkgConfig(g.lang)
-	if err != nil {
-		panic(err)
+	var cgo string
+	switch g.pycfg {
+	case nil:
+		pkgcfg, err := getPkgConfig(g.lang)
+		if err != nil {
+			return err
+		}
+		cgo = fmt.Sprintf("//#cgo pkg-config: %s --cflags --libs", pkgcfg)
+	default:
+		cgo = fmt.Sprintf(
+			"//#cgo CFLAGS: %s\n//#cgo LDFLAGS: %s",
+			cgoFlags(g.pycfg.CFlags),
+			cgoFlags(g.pycfg.LdFlags),
+		)
 	}
 
-	g.Printf(goPreamble, n, pkgcfg, pkgimport)
+	g.Printf(goPreamble, n, cgo, pkgimport)
+	return nil
 }
 
 func (g *goGen) tupleString(tuple []*Var) string {
@@ -0,0 +1,374 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"strings"
+)
+
+const (
+	shimPreamble = `# Package %[1]s is an autogenerated python layer over the _%[1]s extension
+# module of the Go package %[2]s.
+# gopy gen -lang=shim %[2]s
+#
+# File is generated by gopy gen. Do not edit.
+%[3]s
+import sys as _sys
+import types as _types
+
+import _%[1]s
+
+
+class GoError(RuntimeError):
+    """GoError is raised when a Go function returns a non-nil error."""
+
+
+# _classes maps the types of the _%[1]s module to the classes wrapping them.
+_classes = {}
+
+
+def _wrap(v):
+    # returns the python value of the value v of the _%[1]s module.
+    cls = _classes.get(type(v))
+    if cls is None:
+        return v
+    obj = cls.__new__(cls)
+    obj._obj = v
+    return obj
+
+
+def _unwrap(v):
+    # returns the value of the _%[1]s module wrapped by v.
+    if isinstance(v, _Object):
+        return v._obj
+    return v
+
+
+class _Object(object):
+    # _Object wraps a Go value of the _%[1]s module.
+    __slots__ = ("_obj",)
+
+    _type = None  # type of the wrapped values
+    _fields = {}  # names of the fields of the wrapped values, by python name
+
+    def __init__(self, *args, **kwargs):
+        args = [_unwrap(v) for v in args]
+        kwargs = dict((self._fields.get(k, k), _unwrap(v)) for k, v in kwargs.items())
+        self._obj = self._type(*args, **kwargs)
+
+    def __str__(self):
+        return str(self._obj)
+
+    def __repr__(self):
+        return "<%%s.%%s %%s>" %% (type(self).__module__, type(self).__name__, self._obj)
+
+    def to_dict(self):
+        """returns the content of the value as a dict of native python values"""
+        names = dict((v, k) for k, v in self._fields.items())
+        return dict((names.get(k, k), v) for k, v in self._obj.to_dict().items())
+
+    @classmethod
+    def from_dict(cls, d):
+        """creates a new value from the content of the dict d"""
+        d = dict((cls._fields.get(k, k), v) for k, v in d.items())
+        return _wrap(cls._type.from_dict(d))
+`
+)
+
+// GenShim generates a pure-python module exposing the _<package> extension
+// module generated by GenCPythonModule with the conventions of python:
+// structs are wrapped by classes with properties, names are in snake_case
+// (and constants in upper case), and Go errors are raised as GoError.
+func GenShim(w io.Writer, fset *token.FileSet, pkg *Package) error {
+	g := &shimGen{
+		printer: &printer{buf: new(bytes.Buffer), indentEach: []byte("    ")},
+		pkg:     pkg,
+		low:     "_" + pkg.Name(),
+	}
+	g.gen()
+
+	doc := ""
+	if pkg.doc.Doc != "" {
+		doc = "\n" + pyDocString(pkg.doc.Doc) + "\n"
+	}
+	_, err := fmt.Fprintf(w, shimPreamble, pkg.Name(), pkg.ImportPath(), doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, g.buf)
+	return err
+}
+
+// shimGen generates the python layer over the extension module of a package.
+type shimGen struct {
+	*printer
+
+	pkg *Package
+	low string // name of the extension module
+}
+
+func (g *shimGen) gen() {
+	for i, c := range g.pkg.consts {
+		if i == 0 {
+			g.nl(1)
+		}
+		g.genConst(c)
+	}
+
+	// the other named types are exposed as they are.
+	var named []string
+	for _, n := range g.pkg.syms.names() {
+		sym := g.pkg.syms.sym(n)
+		if sym.isType() && sym.isNamed() && !sym.isStruct() && sym.gopkg == g.pkg.pkg {
+			named = append(named, sym.goname)
+		}
+	}
+	if len(named) > 0 {
+		g.nl(2)
+	}
+	for _, name := range named {
+		g.Printf("%[1]s = %[2]s.%[1]s\n", name, g.low)
+	}
+
+	for _, s := range g.pkg.structs {
+		g.genStruct(s)
+	}
+
+	for _, s := range g.pkg.structs {
+		for _, ctor := range s.ctors {
+			g.genFunc(ctor, false)
+		}
+	}
+
+	for _, f := range g.pkg.funcs {
+		g.genFunc(f, false)
+	}
+
+	for _, v := range g.pkg.vars {
+		g.genVar(v)
+	}
+	if len(g.pkg.vars) == 0 {
+		return
+	}
+
+	// expose the variables as attributes of the module.
+	g.nl(2)
+	g.Printf("class _Module(_types.ModuleType):\n")
+	g.Indent()
+	g.Printf("# _Module exposes the variables of the package as module attributes.\n")
+	for _, v := range g.pkg.vars {
+		g.Printf("%s = property(lambda self: %s(), lambda self, v: %s(v))\n",
+			v.pyName(PEP8Naming),
+			PEP8Naming.accessor("get", v.Name(), v.alias),
+			PEP8Naming.accessor("set", v.Name(), v.alias),
+		)
+	}
+	g.Outdent()
+	g.nl(2)
+	g.Printf("_module = _Module(__name__, __doc__)\n")
+	g.Printf("_module.__dict__.update(globals())\n")
+	g.Printf("_module._orig = _sys.modules[__name__]\n")
+	g.Printf("_sys.modules[__name__] = _module\n")
+}
+
+// isStruct reports whether typ is a bound struct of the package, or a pointer
+// to one, whose values are wrapped by a class.
+func (g *shimGen) isStruct(typ types.Type) bool {
+	if p, ok := typ.(*types.Pointer); ok {
+		typ = p.Elem()
+	}
+	for _, s := range g.pkg.structs {
+		if types.Identical(s.GoType(), typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// topy returns the python expression converting the value x of the extension
+// module, of type typ, to python.
+func (g *shimGen) topy(typ types.Type, x string) string {
+	if g.isStruct(typ) {
+		return "_wrap(" + x + ")"
+	}
+	return x
+}
+
+// tolow returns the python expression converting the python value x of type
+// typ to a value of the extension module.
+func (g *shimGen) tolow(typ types.Type, x string) string {
+	if g.isStruct(typ) {
+		return "_unwrap(" + x + ")"
+	}
+	return x
+}
+
+// nl prints n empty lines.
+func (g *shimGen) nl(n int) {
+	g.buf.WriteString(strings.Repeat("\n", n))
+}
+
+// genDoc prints the docstring doc.
+func (g *shimGen) genDoc(doc string) {
+	if strings.TrimSpace(doc) == "" {
+		return
+	}
+	for _, line := range strings.Split(pyDocString(doc), "\n") {
+		if line == "" {
+			g.nl(1)
+			continue
+		}
+		g.Printf("%s\n", line)
+	}
+}
+
+// genComment prints doc as a comment.
+func (g *shimGen) genComment(doc string) {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		g.Printf("%s\n", strings.TrimRight("# "+line, " \t"))
+	}
+}
+
+func (g *shimGen) genConst(c Const) {
+	g.nl(1)
+	g.genComment(c.Doc())
+	g.Printf("%s = %s.%s\n", c.pyName(PEP8Naming), g.low, c.pyName(g.pkg.naming))
+}
+
+func (g *shimGen) genVar(v Var) {
+	typ := v.GoType()
+	get := g.pkg.naming.accessor("get", v.Name(), v.alias)
+	set := g.pkg.naming.accessor("set", v.Name(), v.alias)
+
+	g.nl(2)
+	g.Printf("def %s():\n", PEP8Naming.accessor("get", v.Name(), v.alias))
+	g.Indent()
+	g.genDoc(v.doc)
+	g.Printf("return %s\n", g.topy(typ, fmt.Sprintf("%s.%s()", g.low, get)))
+	g.Outdent()
+
+	g.nl(2)
+	g.Printf("def %s(v):\n", PEP8Naming.accessor("set", v.Name(), v.alias))
+	g.Indent()
+	g.genDoc(v.doc)
+	g.Printf("%s.%s(%s)\n", g.low, set, g.tolow(typ, "v"))
+	g.Outdent()
+}
+
+func (g *shimGen) genStruct(s Struct) {
+	name := s.GoName()
+
+	g.nl(2)
+	g.Printf("class %s(_Object):\n", name)
+	g.Indent()
+	g.genDoc(s.Doc())
+	g.Printf("__slots__ = ()\n")
+	g.Printf("_type = %s.%s\n", g.low, name)
+
+	st := s.Struct()
+	var fields []string // python and low-level names of the fields
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		pf := g.pkg.pyField(st, i, g.pkg.naming)
+		if pf.hidden {
+			continue
+		}
+		pyname := g.pkg.pyField(st, i, PEP8Naming).name
+		fields = append(fields, fmt.Sprintf("%s: %s", pyQuote(pyname), pyQuote(pf.name)))
+
+		g.nl(1)
+		g.Printf("@property\n")
+		g.Printf("def %s(self):\n", pyname)
+		g.Indent()
+		g.genDoc(s.FieldDoc(i))
+		g.Printf("return %s\n", g.topy(f.Type(), "self._obj."+pf.name))
+		g.Outdent()
+		if pf.readonly {
+			continue
+		}
+		g.nl(1)
+		g.Printf("@%s.setter\n", pyname)
+		g.Printf("def %s(self, v):\n", pyname)
+		g.Indent()
+		g.Printf("self._obj.%s = %s\n", pf.name, g.tolow(f.Type(), "v"))
+		g.Outdent()
+	}
+	if len(fields) > 0 {
+		g.nl(1)
+		g.Printf("_fields = {%s}\n", strings.Join(fields, ", "))
+	}
+
+	for _, m := range s.meths {
+		g.genFunc(m, true)
+	}
+	g.Outdent()
+	g.nl(2)
+	g.Printf("_classes[%s.%s] = %s\n", g.low, name, name)
+}
+
+// genFunc generates the python function calling the function f of the
+// extension module or, if meth is true, the method f of the enclosing class.
+func (g *shimGen) genFunc(f Func, meth bool) {
+	var (
+		params []string
+		args   []string
+	)
+	used := make(map[string]bool)
+	if meth {
+		params = append(params, "self")
+		used["self"] = true
+	}
+	for i, p := range f.Signature().Params() {
+		pname := pyName(snakeName(p.Name()))
+		if pname == "" || pname == "_" || used[pname] {
+			pname = fmt.Sprintf("arg%d", i)
+		}
+		used[pname] = true
+		params = append(params, pname)
+		args = append(args, g.tolow(p.GoType(), pname))
+	}
+
+	call := fmt.Sprintf("%s.%s(%s)", g.low, f.pyName(g.pkg.naming), strings.Join(args, ", "))
+	switch {
+	case meth && f.prop:
+		g.nl(1)
+		g.Printf("@property\n")
+		call = fmt.Sprintf("self._obj.%s", f.pyName(g.pkg.naming))
+	case meth:
+		g.nl(1)
+		call = fmt.Sprintf("self._obj.%s(%s)", f.pyName(g.pkg.naming), strings.Join(args, ", "))
+	default:
+		g.nl(2)
+	}
+	g.Printf("def %s(%s):\n", f.pyName(PEP8Naming), strings.Join(params, ", "))
+	g.Indent()
+	g.genDoc(funcDoc(f))
+	if f.err {
+		g.Printf("try:\n")
+		g.Indent()
+	}
+	if f.Return() != nil {
+		g.Printf("return %s\n", g.topy(f.Return(), call))
+	} else {
+		g.Printf("%s\n", call)
+	}
+	if f.err {
+		g.Outdent()
+		g.Printf("except RuntimeError as err:\n")
+		g.Indent()
+		g.Printf("raise GoError(*err.args)\n")
+		g.Outdent()
+	}
+	g.Outdent()
+}
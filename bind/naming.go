@@ -0,0 +1,401 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Naming is the convention used for the python names of the declarations
+// of a package.
+type Naming int
+
+const (
+	// GoNaming keeps the Go names of the declarations.
+	GoNaming Naming = iota
+
+	// PEP8Naming exposes functions, methods, fields and variables with
+	// snake_case names, and constants with UPPER_CASE names.
+	// Type names are kept as they are.
+	PEP8Naming
+)
+
+// ParseNaming returns the naming convention called name: "go" or "pep8".
+func ParseNaming(name string) (Naming, error) {
+	switch name {
+	case "", "go":
+		return GoNaming, nil
+	case "pep8":
+		return PEP8Naming, nil
+	}
+	return GoNaming, fmt.Errorf("gopy: unknown naming %q (expected go or pep8)", name)
+}
+
+func (n Naming) String() string {
+	switch n {
+	case GoNaming:
+		return "go"
+	case PEP8Naming:
+		return "pep8"
+	}
+	return fmt.Sprintf("Naming(%d)", int(n))
+}
+
+// name returns the python name of the function, method, field or variable
+// name, or alias when it is set by a //gopy:name directive.
+// Names of module attributes (module is true) are also kept from shadowing
+// the python builtins.
+func (n Naming) name(name, alias string, module bool) string {
+	if alias != "" {
+		return alias
+	}
+	if n == PEP8Naming {
+		name = snakeName(name)
+		if module && pyBuiltins[name] {
+			return name + "_"
+		}
+	}
+	return pyName(name)
+}
+
+// constName returns the python name of the constant name, or alias when it
+// is set by a //gopy:name directive.
+func (n Naming) constName(name, alias string) string {
+	if alias != "" {
+		return alias
+	}
+	if n == PEP8Naming {
+		name = strings.ToUpper(snakeName(name))
+	}
+	return pyName(name)
+}
+
+// accessor returns the name of the function of the python module getting
+// (verb is "get") or setting (verb is "set") the constant or variable name,
+// or alias when it is set by a //gopy:name directive.
+func (n Naming) accessor(verb, name, alias string) string {
+	if alias != "" {
+		name = alias
+	}
+	if n == PEP8Naming {
+		return verb + "_" + snakeName(name)
+	}
+	return strings.ToUpper(verb[:1]) + verb[1:] + name
+}
+
+// pyName returns the python name of the function or method f.
+func (f Func) pyName(n Naming) string {
+	return n.name(f.name, f.alias, f.sig.Recv() == nil)
+}
+
+// pyName returns the python name of the constant c.
+func (c Const) pyName(n Naming) string {
+	return n.constName(c.GoName(), c.alias)
+}
+
+// pyName returns the python name of the variable v.
+func (v *Var) pyName(n Naming) string {
+	return n.name(v.name, v.alias, true)
+}
+
+// pyIdent matches the valid python identifiers.
+var pyIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// addAliases collects the python names set by the configuration, which
+// override the //gopy:name directives.
+func (p *Package) addAliases() {
+	for key, alias := range p.cfg.Names {
+		p.setAlias(key, alias)
+	}
+}
+
+// setAlias sets the python name of the declaration, field or method key.
+func (p *Package) setAlias(key, alias string) {
+	if v, ok := lookupDecl(p.pkg, key).(*types.Var); ok && v.IsField() {
+		pf := p.fields[v.Origin()]
+		pf.name = alias
+		p.fields[v.Origin()] = pf
+		return
+	}
+	p.aliases[key] = alias
+}
+
+// alias returns the python name set by a //gopy:name directive, or by the
+// configuration, for obj, a package-level declaration or a method of the
+// type parent.
+func (p *Package) alias(parent string, obj types.Object) string {
+	return p.aliases[p.declKey(recvParent(parent, obj), obj.Name())]
+}
+
+// recvParent returns parent if obj is a method, and "" otherwise.
+func recvParent(parent string, obj types.Object) string {
+	if fn, ok := obj.(*types.Func); !ok || fn.Type().(*types.Signature).Recv() == nil {
+		return ""
+	}
+	return parent
+}
+
+// declKey returns the name of the declaration name of the package, or of
+// the field or method name of the type parent, as used by the //gopy:name
+// directives and the configuration (Type.Name for fields and methods, with
+// the name of the generic type for its instances).
+func (p *Package) declKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	if origin, ok := p.origins[parent]; ok {
+		parent = origin
+	}
+	return parent + "." + name
+}
+
+// checkNames skips the declarations, fields and methods whose python names
+// are already used in their scope (the module, or the class of their type)
+// by a type or by a declaration, field or method declared before them.
+// The declarations must be sorted by position.
+func (p *Package) checkNames() {
+	n := p.naming
+	module := make(map[string]string) // owners of the module names
+	for _, name := range p.syms.names() {
+		if sym := p.syms.sym(name); sym.isType() {
+			module[sym.goname] = "type " + sym.goname
+		}
+	}
+	classes := make(map[string]map[string]string) // owners of the class names
+	for _, s := range p.structs {
+		classes[s.GoName()] = map[string]string{
+			"to_dict":   "the to_dict method",
+			"from_dict": "the from_dict method",
+		}
+	}
+
+	for i := range p.decls {
+		d := &p.decls[i]
+		if d.Status != Bound && d.Status != Partial {
+			continue
+		}
+		scope := module
+		var names []string
+		switch d.Kind {
+		case "func":
+			if f, ok := p.lookupFunc(d.Name); ok {
+				names = []string{f.pyName(n)}
+			}
+		case "const":
+			for _, c := range p.consts {
+				if c.GoName() != d.Name {
+					continue
+				}
+				names = []string{c.pyName(n)}
+				if _, big := c.bigInt(); !big {
+					names = append(names, n.accessor("get", c.GoName(), c.alias))
+				}
+			}
+		case "var":
+			for _, v := range p.vars {
+				if v.Name() != d.Name {
+					continue
+				}
+				names = []string{
+					v.pyName(n),
+					n.accessor("get", v.Name(), v.alias),
+					n.accessor("set", v.Name(), v.alias),
+				}
+			}
+		case "field", "method":
+			tname, name := splitKey(d.Name)
+			if classes[tname] == nil {
+				classes[tname] = make(map[string]string)
+			}
+			scope = classes[tname]
+			if d.Kind == "method" {
+				names = []string{n.name(name, p.aliases[p.declKey(tname, name)], false)}
+			} else if pf, ok := p.memberField(tname, name); ok && !pf.hidden {
+				names = []string{pf.name}
+			}
+		}
+
+		owner := ""
+		for _, name := range names {
+			if owner = scope[name]; owner != "" {
+				p.skipName(d, fmt.Sprintf("python name %s already used by %s", name, owner))
+				break
+			}
+		}
+		if owner != "" {
+			continue
+		}
+		for _, name := range names {
+			scope[name] = d.Kind + " " + d.Name
+		}
+	}
+}
+
+// skipName skips the declaration d, whose python name is already used.
+func (p *Package) skipName(d *Decl, reason string) {
+	d.Status, d.Reason = Skipped, reason
+	switch d.Kind {
+	case "func":
+		p.funcs = removeFunc(p.funcs, d.Name)
+		for i := range p.structs {
+			p.structs[i].ctors = removeFunc(p.structs[i].ctors, d.Name)
+		}
+	case "const":
+		consts := p.consts[:0]
+		for _, c := range p.consts {
+			if c.GoName() != d.Name {
+				consts = append(consts, c)
+			}
+		}
+		p.consts = consts
+		for i := range p.enums {
+			consts := p.enums[i].consts[:0]
+			for _, c := range p.enums[i].consts {
+				if c.GoName() != d.Name {
+					consts = append(consts, c)
+				}
+			}
+			p.enums[i].consts = consts
+		}
+	case "var":
+		vars := p.vars[:0]
+		for _, v := range p.vars {
+			if v.Name() != d.Name {
+				vars = append(vars, v)
+			}
+		}
+		p.vars = vars
+	case "field", "method":
+		tname, name := splitKey(d.Name)
+		obj, _ := lookupDecl(p.pkg, p.declKey(tname, name)).(*types.Var)
+		if m, ok := lookupDecl(p.pkg, p.declKey(tname, name)).(*types.Func); ok {
+			p.syms.hidden[m.Origin()] = true
+			for i := range p.structs {
+				if p.structs[i].GoName() == tname {
+					p.structs[i].meths = removeFunc(p.structs[i].meths, name)
+				}
+			}
+		}
+		if obj != nil {
+			pf := p.fields[obj.Origin()]
+			pf.hidden = true
+			p.fields[obj.Origin()] = pf
+		}
+		for i := range p.decls {
+			t := &p.decls[i]
+			if t.Kind != "type" || t.Name != tname {
+				continue
+			}
+			switch t.Status {
+			case Bound:
+				if t.Reason != "" {
+					t.Reason += ", "
+				}
+				t.Status, t.Reason = Partial, t.Reason+"not bound: "+name
+			case Partial:
+				t.Reason += ", " + name
+			}
+		}
+	}
+}
+
+// lookupFunc returns the function, or constructor, name of the package.
+func (p *Package) lookupFunc(name string) (Func, bool) {
+	for _, f := range p.funcs {
+		if f.GoName() == name {
+			return f, true
+		}
+	}
+	for _, s := range p.structs {
+		for _, f := range s.ctors {
+			if f.GoName() == name {
+				return f, true
+			}
+		}
+	}
+	return Func{}, false
+}
+
+// memberField returns how the field name of the struct type tname (possibly
+// an instantiated generic type) is exposed to python.
+func (p *Package) memberField(tname, name string) (pyField, bool) {
+	for _, s := range p.structs {
+		if s.GoName() != tname {
+			continue
+		}
+		st := s.Struct()
+		for i := 0; i < st.NumFields(); i++ {
+			if st.Field(i).Name() == name {
+				return p.pyField(st, i, p.naming), true
+			}
+		}
+	}
+	return pyField{}, false
+}
+
+// removeFunc returns funcs without the function named name.
+func removeFunc(funcs []Func, name string) []Func {
+	list := funcs[:0]
+	for _, f := range funcs {
+		if f.GoName() != name {
+			list = append(list, f)
+		}
+	}
+	return list
+}
+
+// splitKey splits the key Type.Name of a field or method.
+func splitKey(key string) (tname, name string) {
+	i := strings.Index(key, ".")
+	return key[:i], key[i+1:]
+}
+
+// pyBuiltins lists the python 2 and 3 builtin functions and constants which
+// module attributes should not shadow.
+var pyBuiltins = map[string]bool{
+	"abs": true, "all": true, "any": true, "apply": true, "ascii": true,
+	"basestring": true, "bin": true, "bool": true, "breakpoint": true,
+	"buffer": true, "bytearray": true, "bytes": true, "callable": true,
+	"chr": true, "classmethod": true, "cmp": true, "coerce": true,
+	"compile": true, "complex": true, "copyright": true, "credits": true,
+	"delattr": true, "dict": true, "dir": true, "divmod": true,
+	"enumerate": true, "eval": true, "execfile": true, "exit": true,
+	"file": true, "filter": true, "float": true, "format": true,
+	"frozenset": true, "getattr": true, "globals": true, "hasattr": true,
+	"hash": true, "help": true, "hex": true, "id": true, "input": true,
+	"int": true, "intern": true, "isinstance": true, "issubclass": true,
+	"iter": true, "len": true, "license": true, "list": true, "locals": true,
+	"long": true, "map": true, "max": true, "memoryview": true, "min": true,
+	"next": true, "object": true, "oct": true, "open": true, "ord": true,
+	"pow": true, "property": true, "quit": true, "range": true,
+	"raw_input": true, "reduce": true, "reload": true, "repr": true,
+	"reversed": true, "round": true, "set": true, "setattr": true,
+	"slice": true, "sorted": true, "staticmethod": true, "str": true,
+	"sum": true, "super": true, "tuple": true, "type": true, "unichr": true,
+	"unicode": true, "vars": true, "xrange": true, "zip": true,
+}
+
+// snakeName returns the snake_case form of the Go identifier name (e.g.
+// "new_http_server" for NewHTTPServer).
+func snakeName(name string) string {
+	rs := []rune(name)
+	var buf bytes.Buffer
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1]) ||
+				unicode.IsUpper(rs[i-1]) && i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+				buf.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
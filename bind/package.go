@@ -6,31 +6,77 @@ package bind
 
 import (
 	"fmt"
+	"go/ast"
+	"go/constant"
 	"go/doc"
+	"go/token"
+	"go/types"
 	"reflect"
+	"sort"
 	"strings"
-
-	"golang.org/x/tools/go/types"
 )
 
 // Package ties types.Package and ast.Package together.
 // Package also collects informations about structs and funcs.
 type Package struct {
-	pkg *types.Package
-	n   int // number of entities to wrap
-	sz  types.Sizes
-	doc *doc.Package
+	pkg  *types.Package
+	n    int // number of entities to wrap
+	sz   types.Sizes
+	doc  *doc.Package
+	fset *token.FileSet
+
+	// decls records how the exported declarations are exposed to python
+	decls []Decl
+
+	// origins maps the names of the instantiated generic types to the names
+	// of their generic types
+	origins map[string]string
+
+	// insts maps the names of the instantiated generic types to their types
+	insts map[string]*types.Named
+
+	// aliases maps the names of the declarations (Name or Type.Method) to
+	// the python names set by their //gopy:name directives or by the
+	// configuration
+	aliases map[string]string
+	naming  Naming  // naming convention of the python names
+	cfg     *Config // binding configuration
+
+	// fields records the struct fields hidden or renamed by the configuration
+	// or by directives
+	fields map[*types.Var]pyField
+	dirs   directiveSet // bindings requested by the //gopy: directives
 
 	syms    *symtab
 	objs    map[string]Object
 	consts  []Const
+	enums   []Enum
 	vars    []Var
 	structs []Struct
 	funcs   []Func
 }
 
 // NewPackage creates a new Package, tying types.Package and ast.Package together.
-func NewPackage(pkg *types.Package, doc *doc.Package) (*Package, error) {
+// Declarations which can not be exposed to python are skipped: they are
+// reported, with their position in fset, by Package.Decls and Package.Warnings.
+func NewPackage(fset *token.FileSet, pkg *types.Package, doc *doc.Package) (*Package, error) {
+	return NewPackageConfig(fset, pkg, doc, nil)
+}
+
+// NewPackageConfig is like NewPackage, but binds the package as described by
+// the configuration cfg (if not nil).
+func NewPackageConfig(fset *token.FileSet, pkg *types.Package, doc *doc.Package, cfg *Config) (*Package, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if err := cfg.check(pkg); err != nil {
+		return nil, err
+	}
+	naming, err := ParseNaming(cfg.Naming)
+	if err != nil {
+		return nil, err
+	}
+
 	universe.pkg = pkg // FIXME(sbinet)
 	sz := int64(reflect.TypeOf(int(0)).Size())
 	p := &Package{
@@ -38,16 +84,292 @@ func NewPackage(pkg *types.Package, doc *doc.Package) (*Package, error) {
 		n:    0,
 		sz:   &types.StdSizes{sz, sz},
 		doc:  doc,
+		fset: fset,
 		syms: newSymtab(pkg, nil),
 		objs: map[string]Object{},
+
+		origins: make(map[string]string),
+		insts:   make(map[string]*types.Named),
+		aliases: make(map[string]string),
+		naming:  naming,
+		cfg:     cfg,
+		fields:  make(map[*types.Var]pyField),
+		dirs:    newDirectiveSet(),
 	}
-	err := p.process()
+	err = p.process()
 	if err != nil {
 		return nil, err
 	}
+	sort.Stable(byPos(p.decls))
+	p.checkNames()
 	return p, err
 }
 
+// Config returns the binding configuration of the package.
+func (p *Package) Config() *Config {
+	return p.cfg
+}
+
+// Naming returns the naming convention of the python names of the package.
+func (p *Package) Naming() Naming {
+	return p.naming
+}
+
+// SetNaming sets the naming convention of the python names of the package.
+// Names set by //gopy:name directives are kept whatever the naming.
+// The declarations are collected again, as the python names clashing with
+// each other depend on the naming.
+func (p *Package) SetNaming(n Naming) {
+	if n == p.naming {
+		return
+	}
+	cfg := *p.cfg
+	cfg.Naming = n.String()
+	np, err := NewPackageConfig(p.fset, p.pkg, p.doc, &cfg)
+	if err != nil {
+		// the package was already processed with this configuration.
+		panic(err)
+	}
+	*p = *np
+}
+
+// Decls returns how the exported declarations of the package, and the
+// exported fields and methods of its types, are exposed to python.
+// Declarations are sorted by position.
+func (p *Package) Decls() []Decl {
+	return p.decls
+}
+
+// Warnings returns the list of declarations which were skipped because
+// they can not be exposed to python.
+func (p *Package) Warnings() ErrorList {
+	var list ErrorList
+	for _, d := range p.decls {
+		if d.Status != Skipped {
+			continue
+		}
+		name := d.Name
+		if d.Kind == "field" || d.Kind == "method" {
+			name = d.Kind + " " + name
+		}
+		list = append(list, fmt.Errorf("%v: skipping %s: %s", d.Pos, name, d.Reason))
+	}
+	return list
+}
+
+// addDecl records how the declaration obj is exposed to python.
+func (p *Package) addDecl(obj types.Object, name string, status Status, reason string) {
+	p.addDeclAt(obj.Pos(), declKind(obj), name, status, reason)
+}
+
+func (p *Package) addDeclAt(pos token.Pos, kind, name string, status Status, reason string) {
+	p.decls = append(p.decls, Decl{
+		Kind:   kind,
+		Name:   name,
+		Status: status,
+		Reason: reason,
+		Pos:    p.fset.Position(pos),
+	})
+}
+
+// instantiateDirective is the prefix of the comments requesting an
+// instantiation of a generic type, e.g.:
+//
+//	//gopy:instantiate Stack[int]
+const instantiateDirective = "//gopy:instantiate "
+
+// addInstances adds to the symbols table the instantiations of the generic
+// type obj requested by the directives in its doc comment.
+func (p *Package) addInstances(obj *types.TypeName) {
+	var names []string
+	for _, c := range p.directives(obj.Name(), instantiateDirective) {
+		expr := strings.TrimSpace(strings.TrimPrefix(c.Text, instantiateDirective))
+		tv, err := types.Eval(p.fset, p.pkg, token.NoPos, expr)
+		if err != nil {
+			p.addDeclAt(c.Pos(), "type", expr, Skipped, err.Error())
+			continue
+		}
+		inst, ok := tv.Type.(*types.Named)
+		if !ok || !tv.IsType() || inst.Origin() != obj.Type() {
+			p.addDeclAt(c.Pos(), "type", expr, Skipped,
+				fmt.Sprintf("not an instantiation of %s", obj.Name()),
+			)
+			continue
+		}
+		if err := checkType(inst); err != nil {
+			p.addDeclAt(c.Pos(), "type", expr, Skipped, err.Error())
+			continue
+		}
+		name := instName(inst)
+		if prev, dup := p.insts[name]; dup {
+			p.addDeclAt(c.Pos(), "type", expr, Skipped,
+				fmt.Sprintf("name %s already used by %s", name, types.TypeString(prev, types.RelativeTo(p.pkg))),
+			)
+			continue
+		}
+		if p.pkg.Scope().Lookup(name) != nil {
+			p.addDeclAt(c.Pos(), "type", expr, Skipped,
+				fmt.Sprintf("name %s already declared in package %s", name, p.pkg.Name()),
+			)
+			continue
+		}
+		p.insts[name] = inst
+		p.syms.addType(types.NewTypeName(obj.Pos(), p.pkg, name, inst), inst)
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		p.addDecl(obj, obj.Name(), Skipped,
+			"generic type (instantiate it with a //gopy:instantiate directive)",
+		)
+		return
+	}
+	p.addDecl(obj, obj.Name(), Bound, "instantiated as "+strings.Join(names, ", "))
+}
+
+// directives returns the comments of the declaration of the type name which
+// start with prefix.
+func (p *Package) directives(name, prefix string) []*ast.Comment {
+	var list []*ast.Comment
+	for _, t := range p.doc.Types {
+		if t.Name != name || t.Decl == nil {
+			continue
+		}
+		groups := []*ast.CommentGroup{t.Decl.Doc}
+		for _, spec := range t.Decl.Specs {
+			if tspec, ok := spec.(*ast.TypeSpec); ok && tspec.Name.Name == name {
+				groups = append(groups, tspec.Doc)
+			}
+		}
+		for _, g := range groups {
+			if g == nil {
+				continue
+			}
+			for _, c := range g.List {
+				if strings.HasPrefix(c.Text, prefix) {
+					list = append(list, c)
+				}
+			}
+		}
+	}
+	return list
+}
+
+// instances returns the instantiated generic types of the symbols table,
+// named after their python names, and records how they are exposed.
+func (p *Package) instances() []*types.TypeName {
+	var objs []*types.TypeName
+	for _, n := range p.syms.names() {
+		sym := p.syms.syms[n]
+		named, ok := sym.GoType().(*types.Named)
+		if !ok || !sym.isType() || (sym.kind&skPointer) != 0 || named.TypeArgs().Len() == 0 {
+			continue
+		}
+		obj := types.NewTypeName(named.Obj().Pos(), p.pkg, sym.goname, named)
+		p.origins[sym.goname] = named.Obj().Name()
+
+		status, reason := Bound, "instance of "+p.syms.typename(named, p.pkg)
+		if skipped := p.checkMembers(obj); len(skipped) > 0 {
+			status = Partial
+			reason += ", not bound: " + strings.Join(skipped, ", ")
+		}
+		p.addDecl(obj, obj.Name(), status, reason)
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
+// check returns an error describing why the package-level declaration obj
+// can not be exposed to python, or nil if it can.
+func (p *Package) check(obj types.Object) error {
+	switch obj := obj.(type) {
+	case *types.Const, *types.Var:
+		return checkType(obj.Type())
+
+	case *types.Func:
+		sig := obj.Type().(*types.Signature)
+		if sig.TypeParams().Len() > 0 {
+			return fmt.Errorf("generic functions are not supported")
+		}
+		return checkSig(sig)
+
+	case *types.TypeName:
+		return checkType(obj.Type())
+	}
+	return fmt.Errorf("unsupported object %v", obj)
+}
+
+// checkMembers records how the exported fields and methods of the type obj
+// are exposed to python, and returns the names of those which are not.
+func (p *Package) checkMembers(obj *types.TypeName) []string {
+	var skipped []string
+	named := obj.Type().(*types.Named)
+	if typ, ok := named.Underlying().(*types.Struct); ok {
+		for i := 0; i < typ.NumFields(); i++ {
+			f := typ.Field(i)
+			if !f.Exported() {
+				continue
+			}
+			name := obj.Name() + "." + f.Name()
+			reason := p.excluded(p.declKey(obj.Name(), f.Name()))
+			excluded := p.usesExcluded(f.Type())
+			switch err := checkType(f.Type()); {
+			case err != nil:
+				p.addDecl(f, name, Skipped, err.Error())
+				skipped = append(skipped, f.Name())
+			case reason != "":
+				p.addDecl(f, name, Hidden, reason)
+			case p.pyField(typ, i, p.naming).hidden:
+				p.addDecl(f, name, Hidden, "")
+			case excluded != "":
+				p.addDecl(f, name, Hidden, "uses the excluded type "+excluded)
+				pf := p.fields[f.Origin()]
+				pf.hidden = true
+				p.fields[f.Origin()] = pf
+			default:
+				p.addDecl(f, name, Bound, "")
+			}
+		}
+	}
+	if typ, ok := named.Underlying().(*types.Interface); ok {
+		// the methods of an interface are not wrapped, but the unsupported
+		// ones are reported all the same.
+		for i := 0; i < typ.NumMethods(); i++ {
+			m := typ.Method(i)
+			if !m.Exported() {
+				continue
+			}
+			if err := checkSig(m.Type().(*types.Signature)); err != nil {
+				p.addDecl(m, obj.Name()+"."+m.Name(), Skipped, err.Error())
+				skipped = append(skipped, m.Name())
+			}
+		}
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		m := named.Method(i)
+		if !m.Exported() {
+			continue
+		}
+		name := obj.Name() + "." + m.Name()
+		if err := checkSig(m.Type().(*types.Signature)); err != nil {
+			p.addDecl(m, name, Skipped, err.Error())
+			skipped = append(skipped, m.Name())
+			continue
+		}
+		if reason := p.excluded(p.declKey(obj.Name(), m.Name())); reason != "" {
+			p.addDecl(m, name, Hidden, reason)
+			continue
+		}
+		if t := p.usesExcluded(m.Type()); t != "" {
+			p.addDecl(m, name, Hidden, "uses the excluded type "+t)
+			p.syms.hidden[m.Origin()] = true
+			continue
+		}
+		p.addDecl(m, name, Bound, "")
+	}
+	return skipped
+}
+
 // Name returns the package name.
 func (p *Package) Name() string {
 	return p.pkg.Name()
@@ -62,6 +384,9 @@ func (p *Package) ImportPath() string {
 // parent is the name of the containing scope ("" for global scope)
 func (p *Package) getDoc(parent string, o types.Object) string {
 	n := o.Name()
+	if origin, ok := p.origins[parent]; ok {
+		parent = origin
+	}
 	switch o.(type) {
 	case *types.Const:
 		for _, c := range p.doc.Consts {
@@ -147,6 +472,9 @@ func (p *Package) getDoc(parent string, o types.Object) string {
 		return doc
 
 	case *types.TypeName:
+		if origin, ok := p.origins[n]; ok {
+			n = origin
+		}
 		for _, t := range p.doc.Types {
 			if n == t.Name {
 				return t.Doc
@@ -161,29 +489,122 @@ func (p *Package) getDoc(parent string, o types.Object) string {
 	return ""
 }
 
+// getFieldDocs returns the doc strings associated with the fields of the
+// struct type obj, indexed by field name.
+// The doc string of a field is made of its doc comment followed by its
+// line comment.
+func (p *Package) getFieldDocs(obj *types.TypeName) map[string]string {
+	docs := make(map[string]string)
+	name := obj.Name()
+	if origin, ok := p.origins[name]; ok {
+		name = origin
+	}
+	for _, t := range p.doc.Types {
+		if t.Name != name || t.Decl == nil {
+			continue
+		}
+		for _, spec := range t.Decl.Specs {
+			tspec, ok := spec.(*ast.TypeSpec)
+			if !ok || tspec.Name.Name != name {
+				continue
+			}
+			styp, ok := tspec.Type.(*ast.StructType)
+			if !ok || styp.Fields == nil {
+				continue
+			}
+			for _, field := range styp.Fields.List {
+				doc := []string{}
+				for _, cg := range []*ast.CommentGroup{field.Doc, field.Comment} {
+					if txt := strings.TrimSpace(cg.Text()); txt != "" {
+						doc = append(doc, txt)
+					}
+				}
+				if len(doc) == 0 {
+					continue
+				}
+				names := []string{}
+				for _, n := range field.Names {
+					names = append(names, n.Name)
+				}
+				if len(names) == 0 {
+					// embedded field: the field name is the type name.
+					if n := embeddedName(field.Type); n != "" {
+						names = append(names, n)
+					}
+				}
+				for _, n := range names {
+					docs[n] = strings.Join(doc, "\n")
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// embeddedName returns the name of the field implicitly declared by an
+// embedded type expression.
+func embeddedName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.StarExpr:
+		return embeddedName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	}
+	return ""
+}
+
 // process collects informations about a go package.
 func (p *Package) process() error {
 	var err error
 
+	p.addDirectives()
+	p.addAliases()
+	p.addExcluded()
+
 	funcs := make(map[string]Func)
 	structs := make(map[string]Struct)
 
 	scope := p.pkg.Scope()
+	names := make([]string, 0, scope.Len())
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
 		if !obj.Exported() {
 			continue
 		}
+		if reason := p.excluded(name); reason != "" {
+			p.addDecl(obj, name, Hidden, reason)
+			continue
+		}
+		if obj, ok := obj.(*types.TypeName); ok && isGeneric(obj.Type()) {
+			p.addInstances(obj)
+			continue
+		}
+		if err := p.check(obj); err != nil {
+			p.addDecl(obj, name, Skipped, err.Error())
+			continue
+		}
+		if t := p.excludedType(obj); t != "" {
+			p.addDecl(obj, name, Hidden, "uses the excluded type "+t)
+			continue
+		}
+		status, reason := Bound, ""
+		if obj, ok := obj.(*types.TypeName); ok {
+			if skipped := p.checkMembers(obj); len(skipped) > 0 {
+				status = Partial
+				reason = "not bound: " + strings.Join(skipped, ", ")
+			}
+		}
+		p.addDecl(obj, name, status, reason)
 
 		p.n++
 		p.syms.addSymbol(obj)
+		names = append(names, name)
 	}
 
-	for _, name := range scope.Names() {
+	for _, name := range names {
 		obj := scope.Lookup(name)
-		if !obj.Exported() {
-			continue
-		}
 
 		switch obj := obj.(type) {
 		case *types.Const:
@@ -223,22 +644,50 @@ func (p *Package) process() error {
 				// ok. handled by p.syms-types
 
 			default:
-				//TODO(sbinet)
-				panic(fmt.Errorf("not yet supported: %v (%T)", typ, obj))
+				return fmt.Errorf("bind: not yet supported: %v (%T)", typ, obj)
 			}
 
 		default:
-			//TODO(sbinet)
-			panic(fmt.Errorf("not yet supported: %v (%T)", obj, obj))
+			return fmt.Errorf("bind: not yet supported: %v (%T)", obj, obj)
+		}
+
+	}
+
+	// instantiated generic types, requested by directives or used in the
+	// signatures of the package.
+	for _, obj := range p.instances() {
+		p.n++
+		if _, ok := obj.Type().Underlying().(*types.Struct); ok {
+			structs[obj.Name()], err = newStruct(p, obj)
+			if err != nil {
+				return err
+			}
 		}
+	}
 
+	// iterate in a fixed order, for the generated code to be reproducible.
+	snames := make([]string, 0, len(structs))
+	for sname := range structs {
+		snames = append(snames, sname)
+	}
+	sort.Strings(snames)
+	fnames := make([]string, 0, len(funcs))
+	for name := range funcs {
+		fnames = append(fnames, name)
 	}
+	sort.Strings(fnames)
 
 	// remove ctors from funcs.
 	// add methods.
-	for sname, s := range structs {
-		for name, fct := range funcs {
-			if fct.Return() == nil {
+	for _, sname := range snames {
+		s := structs[sname]
+		ctors, only := p.ctors(sname)
+		for _, name := range fnames {
+			fct, ok := funcs[name]
+			if !ok || fct.Return() == nil {
+				continue
+			}
+			if only && !contains(ctors, name) {
 				continue
 			}
 			if fct.Return() == s.GoType() {
@@ -249,13 +698,22 @@ func (p *Package) process() error {
 				structs[sname] = s
 			}
 		}
+		for _, name := range ctors {
+			found := false
+			for _, ctor := range s.ctors {
+				found = found || ctor.GoName() == name
+			}
+			if !found {
+				return fmt.Errorf("gopy: %s is not a constructor of %s", name, sname)
+			}
+		}
 
 		ptyp := types.NewPointer(s.GoType())
 		p.syms.addType(nil, ptyp)
 		mset := types.NewMethodSet(ptyp)
 		for i := 0; i < mset.Len(); i++ {
 			meth := mset.At(i)
-			if !meth.Obj().Exported() {
+			if !p.syms.isExposedMethod(meth.Obj().(*types.Func)) {
 				continue
 			}
 			m, err := newFuncFrom(p, sname, meth.Obj(), meth.Type().(*types.Signature))
@@ -270,10 +728,16 @@ func (p *Package) process() error {
 		p.addStruct(s)
 	}
 
-	for _, fct := range funcs {
+	for _, name := range fnames {
+		fct, ok := funcs[name]
+		if !ok {
+			continue
+		}
 		p.addFunc(fct)
 	}
 
+	p.addEnums()
+
 	// attach docstrings to methods
 	for _, n := range p.syms.names() {
 		sym := p.syms.syms[n]
@@ -284,7 +748,7 @@ func (p *Package) process() error {
 		case *types.Named:
 			for i := 0; i < typ.NumMethods(); i++ {
 				m := typ.Method(i)
-				if !m.Exported() {
+				if !p.syms.isExposedMethod(m) {
 					continue
 				}
 				doc := p.getDoc(sym.goname, m)
@@ -307,8 +771,87 @@ func (p *Package) addConst(obj *types.Const) {
 	p.consts = append(p.consts, newConst(p, obj))
 }
 
+// addEnums collects the groups of constants of a same named integer type.
+// Such types are exposed to python as enum classes, provided they do not
+// define any exported method besides String.
+func (p *Package) addEnums() {
+	groups := make(map[*types.TypeName][]Const)
+	names := []*types.TypeName{}
+	for _, c := range p.consts {
+		named, ok := c.obj.Type().(*types.Named)
+		if !ok || named.Obj().Pkg() != p.pkg || !named.Obj().Exported() {
+			continue
+		}
+		btyp, ok := named.Underlying().(*types.Basic)
+		if !ok || btyp.Info()&types.IsInteger == 0 {
+			continue
+		}
+		if _, dup := groups[named.Obj()]; !dup {
+			names = append(names, named.Obj())
+		}
+		groups[named.Obj()] = append(groups[named.Obj()], c)
+	}
+
+	for _, obj := range names {
+		consts := groups[obj]
+		force, ok := p.cfg.Enums[obj.Name()]
+		if ok && !force || len(consts) < 2 && !force {
+			continue
+		}
+		e := Enum{
+			sym:    p.syms.symtype(obj.Type()),
+			consts: consts,
+			flag:   true,
+		}
+		named := obj.Type().(*types.Named)
+		hasMethods := false
+		for i := 0; i < named.NumMethods(); i++ {
+			m := named.Method(i)
+			switch {
+			case !m.Exported():
+				// ignore
+			case isStringer(m) && !isPointerRecv(m):
+				e.str = true
+			default:
+				hasMethods = true
+			}
+		}
+		if hasMethods {
+			continue
+		}
+
+		sort.Stable(byConstValue(e.consts))
+		for _, c := range e.consts {
+			v, ok := constant.Int64Val(c.obj.Val())
+			if !ok || v <= 0 || v&(v-1) != 0 {
+				e.flag = false
+			}
+		}
+
+		// values of enum types are converted to and from enum members.
+		e.sym.pyfmt = "O&"
+		e.sym.pychk = fmt.Sprintf(
+			"(cpy_func_%[1]s_check(%%[1]s) || PyInt_Check(%%[1]s) || PyLong_Check(%%[1]s))",
+			e.sym.id,
+		)
+		p.enums = append(p.enums, e)
+	}
+}
+
+// enum returns the enum associated with the type sym, if any.
+func (p *Package) enum(sym *symbol) (Enum, bool) {
+	for _, e := range p.enums {
+		if e.sym == sym {
+			return e, true
+		}
+	}
+	return Enum{}, false
+}
+
 func (p *Package) addVar(obj *types.Var) {
-	p.vars = append(p.vars, *newVarFrom(p, obj))
+	v := newVarFrom(p, obj)
+	v.alias = p.alias("", obj)
+	p.vars = append(p.vars, *v)
 }
 
 func (p *Package) addStruct(s Struct) {
@@ -342,6 +885,7 @@ type Struct struct {
 
 	id    string
 	doc   string
+	fdocs map[string]string // doc strings of fields
 	ctors []Func
 	meths []Func
 
@@ -355,9 +899,10 @@ func newStruct(p *Package, obj *types.TypeName) (Struct, error) {
 	}
 	sym.doc = p.getDoc("", obj)
 	s := Struct{
-		pkg: p,
-		sym: sym,
-		obj: obj,
+		pkg:   p,
+		sym:   sym,
+		obj:   obj,
+		fdocs: p.getFieldDocs(obj),
 	}
 	return s, nil
 }
@@ -386,6 +931,20 @@ func (s Struct) Struct() *types.Struct {
 	return s.sym.GoType().Underlying().(*types.Struct)
 }
 
+// FieldDoc returns the doc string of the i-th field of the struct,
+// prefixed with the field declaration.
+func (s Struct) FieldDoc(i int) string {
+	f := s.Struct().Field(i)
+	decl := f.Name() + " " + types.TypeString(
+		f.Type(),
+		func(pkg *types.Package) string { return pkg.Name() },
+	)
+	if doc := s.fdocs[f.Name()]; doc != "" {
+		return decl + "\n\n" + doc
+	}
+	return decl
+}
+
 // A Signature represents a (non-builtin) function or method type.
 type Signature struct {
 	ret  []*Var
@@ -401,11 +960,33 @@ func newSignatureFrom(pkg *Package, sig *types.Signature) *Signature {
 
 	return &Signature{
 		ret:  newVarsFrom(pkg, sig.Results()),
-		args: newVarsFrom(pkg, sig.Params()),
+		args: newParamsFrom(pkg, sig),
 		recv: recv,
 	}
 }
 
+// newParamsFrom returns the parameters of sig. Unnamed and blank parameters,
+// and those clashing with the names of the results, are named arg%d, so the
+// generated wrappers can refer to them.
+func newParamsFrom(pkg *Package, sig *types.Signature) []*Var {
+	used := make(map[string]bool)
+	for i := 0; i < sig.Results().Len(); i++ {
+		used[sig.Results().At(i).Name()] = true
+	}
+	params := sig.Params()
+	vars := make([]*Var, 0, params.Len())
+	for i := 0; i < params.Len(); i++ {
+		v := params.At(i)
+		name := v.Name()
+		if name == "" || name == "_" || used[name] {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		used[name] = true
+		vars = append(vars, newVar(pkg, v.Type(), name, name, pkg.getDoc("", v)))
+	}
+	return vars
+}
+
 func newSignature(pkg *Package, recv *Var, params, results []*Var) *Signature {
 	return &Signature{
 		ret:  results,
@@ -433,11 +1014,14 @@ type Func struct {
 	typ  types.Type
 	name string
 
-	id   string
-	doc  string
-	ret  types.Type // return type, if any
-	err  bool       // true if original go func has comma-error
-	ctor bool       // true if this is a newXXX function
+	id    string
+	doc   string
+	alias string     // python name set by a //gopy:name directive
+	ret   types.Type // return type, if any
+	err   bool       // true if original go func has comma-error
+	ctor  bool       // true if this is a newXXX function
+	nogil bool       // true if the GIL is released while the go func runs
+	prop  bool       // true if the method is exposed as a python property
 }
 
 func newFuncFrom(p *Package, parent string, obj types.Object, sig *types.Signature) (Func, error) {
@@ -469,20 +1053,24 @@ func newFuncFrom(p *Package, parent string, obj types.Object, sig *types.Signatu
 		return Func{}, fmt.Errorf("bind: too many results to return: %v", obj)
 	}
 
+	key := p.declKey(recvParent(parent, obj), obj.Name())
 	id := obj.Pkg().Name() + "_" + obj.Name()
 	if parent != "" {
 		id = obj.Pkg().Name() + "_" + parent + "_" + obj.Name()
 	}
 
 	return Func{
-		pkg:  p,
-		sig:  newSignatureFrom(p, sig),
-		typ:  obj.Type(),
-		name: obj.Name(),
-		id:   id,
-		doc:  p.getDoc(parent, obj),
-		ret:  ret,
-		err:  haserr,
+		pkg:   p,
+		sig:   newSignatureFrom(p, sig),
+		typ:   obj.Type(),
+		name:  obj.Name(),
+		id:    id,
+		doc:   p.getDoc(parent, obj),
+		alias: p.alias(parent, obj),
+		ret:   ret,
+		err:   haserr,
+		nogil: p.releaseGIL(key),
+		prop:  p.dirs.props[key],
 	}, nil
 }
 
@@ -515,12 +1103,13 @@ func (f Func) Return() types.Type {
 }
 
 type Const struct {
-	pkg *Package
-	sym *symbol
-	obj *types.Const
-	id  string
-	doc string
-	f   Func
+	pkg   *Package
+	sym   *symbol
+	obj   *types.Const
+	id    string
+	doc   string
+	alias string // python name set by a //gopy:name directive
+	f     Func
 }
 
 func newConst(p *Package, o *types.Const) Const {
@@ -543,13 +1132,53 @@ func newConst(p *Package, o *types.Const) Const {
 	}
 
 	return Const{
-		pkg: p,
-		sym: sym,
-		obj: o,
-		id:  id,
-		doc: doc,
-		f:   fct,
+		pkg:   p,
+		sym:   sym,
+		obj:   o,
+		id:    id,
+		doc:   doc,
+		alias: p.alias("", o),
+		f:     fct,
+	}
+}
+
+// bigInt returns the decimal representation of the value of an untyped
+// integer constant which does not fit into an int64.
+// Such constants can not be retrieved through a Go function.
+func (c Const) bigInt() (string, bool) {
+	typ, ok := c.obj.Type().(*types.Basic)
+	if !ok || typ.Info()&types.IsUntyped == 0 {
+		return "", false
+	}
+	val := c.obj.Val()
+	if val.Kind() != constant.Int {
+		return "", false
+	}
+	if _, ok := constant.Int64Val(val); ok {
+		return "", false
 	}
+	return val.String(), true
+}
+
+// Enum is a named integer type with a group of constants of that type.
+// It is exposed to python as an enum.IntEnum (or enum.IntFlag when all its
+// values are powers of two.)
+type Enum struct {
+	sym    *symbol
+	consts []Const // members of the enum, sorted by value
+	flag   bool    // whether all the values are powers of two
+	str    bool    // whether String() is used for the names of the members
+}
+
+func (e Enum) ID() string     { return e.sym.id }
+func (e Enum) GoName() string { return e.sym.goname }
+
+type byConstValue []Const
+
+func (s byConstValue) Len() int      { return len(s) }
+func (s byConstValue) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byConstValue) Less(i, j int) bool {
+	return constant.Compare(s[i].obj.Val(), token.LSS, s[j].obj.Val())
 }
 
 func (c Const) ID() string         { return c.id }
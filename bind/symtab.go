@@ -6,12 +6,13 @@ package bind
 
 import (
 	"fmt"
+	"go/types"
 	"hash/fnv"
 	"reflect"
 	"sort"
 	"strings"
-
-	"golang.org/x/tools/go/types"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
@@ -215,6 +216,10 @@ type symtab struct {
 	pkg    *types.Package
 	syms   map[string]*symbol
 	parent *symtab
+
+	// hidden records the methods which are not exposed to python, because
+	// of the configuration of the package
+	hidden map[*types.Func]bool
 }
 
 func newSymtab(pkg *types.Package, parent *symtab) *symtab {
@@ -225,6 +230,7 @@ func newSymtab(pkg *types.Package, parent *symtab) *symtab {
 		pkg:    pkg,
 		syms:   make(map[string]*symbol),
 		parent: parent,
+		hidden: make(map[*types.Func]bool),
 	}
 	return s
 }
@@ -278,6 +284,212 @@ func (sym *symtab) symtype(t types.Type) *symbol {
 	return sym.sym(tname)
 }
 
+// mapsyms returns the symbols for the key and element types of a map.
+// ksym is nil if the keys can not be converted from and to python values:
+// only maps with (possibly named) basic keys are supported.
+func (sym *symtab) mapsyms(s *symbol) (ksym, esym *symbol) {
+	typ := s.GoType().Underlying().(*types.Map)
+	esym = sym.symtype(typ.Elem())
+	ksym = sym.symtype(typ.Key())
+	if ksym == nil || !ksym.isBasic() {
+		return nil, esym
+	}
+	bsym := sym.symtype(typ.Key().Underlying())
+	if bsym == nil || bsym.c2py == "" || bsym.py2c == "" {
+		return nil, esym
+	}
+	return ksym, esym
+}
+
+// isWrapped returns whether values of the type s are exposed to python
+// through a gopy type, rather than as native python values.
+func (s symbol) isWrapped() bool {
+	if !s.isType() || isErrorType(s.GoType()) {
+		return false
+	}
+	return !s.isBasic() || s.isNamed()
+}
+
+// checkType returns an error describing why values of type t can not be
+// exposed to python, or nil if they can.
+// Fields of struct types and methods of named types are not checked: the
+// unsupported ones are skipped, but the type itself is still exposed.
+// Pointers are only supported as parameters and results of functions (see
+// checkSig).
+func checkType(t types.Type) error {
+	seen := make(map[*types.Named]bool)
+	var check func(t types.Type) error
+	check = func(t types.Type) error {
+		switch typ := t.(type) {
+		case *types.Basic:
+			if universe.symtype(typ) == nil {
+				return fmt.Errorf("unsupported basic type %s", typ)
+			}
+			return nil
+
+		case *types.Array:
+			return check(typ.Elem())
+
+		case *types.Slice:
+			return check(typ.Elem())
+
+		case *types.Pointer:
+			return fmt.Errorf("unsupported pointer type %s", typ)
+
+		case *types.Map:
+			if err := check(typ.Key()); err != nil {
+				return err
+			}
+			return check(typ.Elem())
+
+		case *types.Signature:
+			return checkSig(typ)
+
+		case *types.Named:
+			if isErrorType(typ) || seen[typ] {
+				return nil
+			}
+			seen[typ] = true
+			if isGeneric(typ) {
+				return fmt.Errorf("generic type %s is not instantiated", typ.Obj().Name())
+			}
+			args := typ.TypeArgs()
+			for i := 0; i < args.Len(); i++ {
+				if err := check(args.At(i)); err != nil {
+					return err
+				}
+			}
+			switch utyp := typ.Underlying().(type) {
+			case *types.Struct, *types.Interface:
+				return nil
+			case *types.Basic, *types.Array, *types.Slice, *types.Signature:
+				return check(utyp)
+			default:
+				return fmt.Errorf(
+					"unsupported type %s.%s (%s)",
+					typ.Obj().Pkg().Name(), typ.Obj().Name(), utyp,
+				)
+			}
+		}
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return check(t)
+}
+
+// isGeneric returns whether t is a generic type which is not instantiated.
+func isGeneric(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.TypeParams().Len() > 0 && named.TypeArgs().Len() == 0
+}
+
+// instName returns the name under which the instantiated generic type t is
+// exposed to python: the name of the generic type followed by the names of
+// its type arguments, spelling out their type constructors (e.g. StackInt
+// for Stack[int], StackSliceInt for Stack[[]int] and StackMapStringInt for
+// Stack[map[string]int].)
+func instName(t *types.Named) string {
+	name := t.Obj().Name()
+	args := t.TypeArgs()
+	for i := 0; i < args.Len(); i++ {
+		name += typeArgName(args.At(i))
+	}
+	return name
+}
+
+// typeArgName returns the capitalized name of the type argument t in the
+// name of an instantiated generic type.
+func typeArgName(t types.Type) string {
+	switch t := types.Unalias(t).(type) {
+	case *types.Basic:
+		return capitalize(t.Name())
+	case *types.Named:
+		return capitalize(instName(t))
+	case *types.Pointer:
+		return "Ptr" + typeArgName(t.Elem())
+	case *types.Slice:
+		return "Slice" + typeArgName(t.Elem())
+	case *types.Array:
+		return fmt.Sprintf("Array%d", t.Len()) + typeArgName(t.Elem())
+	case *types.Map:
+		return "Map" + typeArgName(t.Key()) + typeArgName(t.Elem())
+	case *types.Chan:
+		return "Chan" + typeArgName(t.Elem())
+	case *types.Signature:
+		name := "Func"
+		for i := 0; i < t.Params().Len(); i++ {
+			name += typeArgName(t.Params().At(i).Type())
+		}
+		if t.Results().Len() > 0 {
+			name += "To"
+			for i := 0; i < t.Results().Len(); i++ {
+				name += typeArgName(t.Results().At(i).Type())
+			}
+		}
+		return name
+	case *types.Interface:
+		if t.Empty() {
+			return "Any"
+		}
+		return "Interface"
+	case *types.Struct:
+		return "Struct"
+	}
+	return ""
+}
+
+// capitalize returns s with its first letter in upper case.
+func capitalize(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+// checkPointer returns an error describing why values of type t, a
+// parameter or result of a function, can not be exposed to python, or nil if
+// they can. Pointers to struct types are exposed as the struct values.
+func checkPointer(t types.Type) error {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return checkType(t)
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return fmt.Errorf("unsupported pointer to non-struct type %s", ptr.Elem())
+	}
+	if _, ok := named.Underlying().(*types.Struct); !ok {
+		return fmt.Errorf("unsupported pointer to non-struct type %s", ptr.Elem())
+	}
+	return checkType(named)
+}
+
+// checkSig returns an error describing why functions with signature sig can
+// not be exposed to python, or nil if they can.
+func checkSig(sig *types.Signature) error {
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if err := checkPointer(params.At(i).Type()); err != nil {
+			return err
+		}
+	}
+	res := sig.Results()
+	switch {
+	case res.Len() > 2:
+		return fmt.Errorf("too many results (%d)", res.Len())
+	case res.Len() == 2 && !isErrorType(res.At(1).Type()):
+		return fmt.Errorf("second result must be of type error, got %s", res.At(1).Type())
+	}
+	for i := 0; i < res.Len(); i++ {
+		if err := checkPointer(res.At(i).Type()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isExposedMethod returns whether the method m is exposed to python.
+func (sym *symtab) isExposedMethod(m *types.Func) bool {
+	return m.Exported() && checkSig(m.Type().(*types.Signature)) == nil && !sym.hidden[m.Origin()]
+}
+
 func (sym *symtab) addSymbol(obj types.Object) {
 	fn := types.ObjectString(obj, nil)
 	n := obj.Name()
@@ -350,6 +562,9 @@ func (sym *symtab) processTuple(tuple *types.Tuple) {
 func (sym *symtab) addType(obj types.Object, t types.Type) {
 	fn := sym.typename(t, nil)
 	n := sym.typename(t, sym.pkg)
+	if typ, ok := t.(*types.Named); ok && typ.TypeArgs().Len() > 0 {
+		n = instName(typ)
+	}
 	var pkg *types.Package
 	if obj != nil {
 		pkg = obj.Pkg()
@@ -423,7 +638,7 @@ func (sym *symtab) addType(obj types.Object, t types.Type) {
 		// add methods
 		for i := 0; i < typ.NumMethods(); i++ {
 			m := typ.Method(i)
-			if !m.Exported() {
+			if !sym.isExposedMethod(m) {
 				continue
 			}
 			if true {
@@ -452,11 +667,16 @@ func (sym *symtab) addArrayType(pkg *types.Package, obj types.Object, t types.Ty
 	elt := sym.sym(enam)
 	if elt == nil || elt.goname == "" {
 		eltname := sym.typename(typ.Elem(), pkg)
-		eobj := sym.pkg.Scope().Lookup(eltname)
-		if eobj == nil {
+		switch eobj := sym.pkg.Scope().Lookup(eltname); {
+		case eobj != nil:
+			sym.addSymbol(eobj)
+		case checkType(typ.Elem()) == nil:
+			// unnamed element types, e.g. of the instantiations of
+			// generic types.
+			sym.addType(nil, typ.Elem())
+		default:
 			panic(fmt.Errorf("could not look-up %q!\n", enam))
 		}
-		sym.addSymbol(eobj)
 		elt = sym.sym(enam)
 		if elt == nil {
 			panic(fmt.Errorf(
@@ -531,11 +751,16 @@ func (sym *symtab) addSliceType(pkg *types.Package, obj types.Object, t types.Ty
 	elt := sym.sym(enam)
 	if elt == nil || elt.goname == "" {
 		eltname := sym.typename(typ.Elem(), pkg)
-		eobj := sym.pkg.Scope().Lookup(eltname)
-		if eobj == nil {
+		switch eobj := sym.pkg.Scope().Lookup(eltname); {
+		case eobj != nil:
+			sym.addSymbol(eobj)
+		case checkType(typ.Elem()) == nil:
+			// unnamed element types, e.g. of the instantiations of
+			// generic types.
+			sym.addType(nil, typ.Elem())
+		default:
 			panic(fmt.Errorf("could not look-up %q!\n", enam))
 		}
-		sym.addSymbol(eobj)
 		elt = sym.sym(enam)
 		if elt == nil {
 			panic(fmt.Errorf(
@@ -570,6 +795,9 @@ func (sym *symtab) addStructType(pkg *types.Package, obj types.Object, t types.T
 	pybuf := make([]string, 0, typ.NumFields())
 	for i := 0; i < typ.NumFields(); i++ {
 		ftyp := typ.Field(i).Type()
+		if checkType(ftyp) != nil {
+			continue
+		}
 		fsym := sym.symtype(ftyp)
 		if fsym == nil {
 			sym.addType(typ.Field(i), ftyp)
@@ -956,8 +1184,8 @@ func init() {
 			goname:  "complex64",
 			cpyname: "float complex",
 			cgoname: "GoComplex64",
-			pyfmt:   "D",
-			pybuf:   "ff",
+			pyfmt:   "O&",
+			pybuf:   "Zf",
 			pysig:   "complex",
 			c2py:    "cgopy_cnv_c2py_complex64",
 			py2c:    "cgopy_cnv_py2c_complex64",
@@ -972,8 +1200,8 @@ func init() {
 			goname:  "complex128",
 			cpyname: "double complex",
 			cgoname: "GoComplex128",
-			pyfmt:   "D",
-			pybuf:   "dd",
+			pyfmt:   "O&",
+			pybuf:   "Zd",
 			pysig:   "complex",
 			c2py:    "cgopy_cnv_c2py_complex128",
 			py2c:    "cgopy_cnv_py2c_complex128",
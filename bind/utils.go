@@ -8,18 +8,28 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"go/types"
 	"io"
 	"os/exec"
+	"reflect"
 	"regexp"
 	"sort"
-
-	"golang.org/x/tools/go/types"
+	"strconv"
+	"strings"
 )
 
 func isErrorType(typ types.Type) bool {
 	return typ == types.Universe.Lookup("error").Type()
 }
 
+func isComplexType(typ types.Type) bool {
+	btyp, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	return btyp.Info()&types.IsComplex != 0
+}
+
 func isStringer(obj types.Object) bool {
 	switch obj := obj.(type) {
 	case *types.Func:
@@ -52,6 +62,15 @@ func isStringer(obj types.Object) bool {
 	return false
 }
 
+func isPointerRecv(m *types.Func) bool {
+	sig := m.Type().(*types.Signature)
+	if sig.Recv() == nil {
+		return false
+	}
+	_, ok := sig.Recv().Type().(*types.Pointer)
+	return ok
+}
+
 func hasError(sig *types.Signature) bool {
 	res := sig.Results()
 	if res == nil || res.Len() <= 0 {
@@ -149,3 +168,88 @@ func getPkgConfig(vers int) (string, error) {
 
 	return pkgcfg, nil
 }
+
+// pyField describes how a struct field is exposed to python, as controlled
+// by the naming of the package and its 'py' struct tag:
+//
+//	py:"name"           exposes the field as the 'name' attribute
+//	py:"-"              hides the field
+//	py:",readonly"      exposes the field without a setter
+type pyField struct {
+	name     string // name of the python attribute
+	readonly bool   // whether the attribute can not be set from python
+	hidden   bool   // whether the field is not exposed to python
+}
+
+// newPyField returns how the i-th field of a struct is exposed to python
+// with the naming n.
+// Unexported fields and fields of unsupported types are always hidden.
+func newPyField(typ *types.Struct, i int, n Naming) pyField {
+	field := typ.Field(i)
+	pf := pyField{
+		name:   n.name(field.Name(), "", false),
+		hidden: !field.Exported() || checkType(field.Type()) != nil,
+	}
+	tag := reflect.StructTag(typ.Tag(i)).Get("py")
+	if tag == "-" {
+		pf.hidden = true
+		return pf
+	}
+	opts := strings.Split(tag, ",")
+	if opts[0] != "" {
+		pf.name = opts[0]
+	}
+	for _, opt := range opts[1:] {
+		if opt == "readonly" {
+			pf.readonly = true
+		}
+	}
+	return pf
+}
+
+// pyField returns how the i-th field of a struct of the package is exposed to
+// python with the naming n, once hidden or renamed by the configuration.
+func (p *Package) pyField(typ *types.Struct, i int, n Naming) pyField {
+	pf := newPyField(typ, i, n)
+	cfg := p.fields[typ.Field(i).Origin()]
+	if cfg.name != "" {
+		pf.name = cfg.name
+	}
+	pf.hidden = pf.hidden || cfg.hidden
+	return pf
+}
+
+// dictKey returns the key under which the i-th field of a struct is stored
+// when converting to and from python dicts.
+// Following encoding/json, the key is taken from the 'json' struct tag when
+// present and the field is skipped (ok == false) when that tag is "-".
+// Otherwise, the python name of the field with the naming n is used.
+func (p *Package) dictKey(typ *types.Struct, i int, n Naming) (key string, ok bool) {
+	pf := p.pyField(typ, i, n)
+	if pf.hidden {
+		return "", false
+	}
+	tag := reflect.StructTag(typ.Tag(i)).Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if idx := strings.Index(tag, ","); idx >= 0 {
+		tag = tag[:idx]
+	}
+	if tag == "" {
+		return pf.name, true
+	}
+	return tag, true
+}
+
+// cgoFlags returns flags formatted for a #cgo directive.
+func cgoFlags(flags []string) string {
+	o := make([]string, len(flags))
+	for i, f := range flags {
+		if strings.ContainsAny(f, " \t'\"") {
+			f = strconv.Quote(f)
+		}
+		o[i] = f
+	}
+	return strings.Join(o, " ")
+}
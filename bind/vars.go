@@ -6,16 +6,16 @@ package bind
 
 import (
 	"fmt"
-
-	"golang.org/x/tools/go/types"
+	"go/types"
 )
 
 type Var struct {
-	pkg  *Package
-	sym  *symbol // symbol associated with var's type
-	id   string
-	doc  string
-	name string
+	pkg   *Package
+	sym   *symbol // symbol associated with var's type
+	id    string
+	doc   string
+	name  string
+	alias string // python name set by a //gopy:name directive
 }
 
 func (v *Var) Name() string {
@@ -53,7 +53,7 @@ func getTypeString(t types.Type) string {
 }
 
 func (v *Var) GoType() types.Type {
-	return v.sym.goobj.Type()
+	return v.sym.GoType()
 }
 
 func (v *Var) CType() string {
@@ -126,6 +126,12 @@ func (v *Var) getFuncArg() string {
 	return "c_" + v.Name()
 }
 
+// isPointer returns whether v is a pointer to a struct, exposed to python as
+// the struct value it points to.
+func (v *Var) isPointer() bool {
+	return v.sym.kind&skPointer != 0
+}
+
 func (v *Var) needWrap() bool {
 	typ := v.GoType()
 	return needWrapType(typ)
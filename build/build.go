@@ -0,0 +1,464 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package build generates and builds (C)Python bindings for Go packages.
+//
+// It implements the gen and bind commands of gopy, and can be used to drive
+// gopy from a Go program:
+//
+//	res, err := build.Bind(ctx, build.Options{
+//		Path:   "github.com/go-python/gopy/_examples/hi",
+//		Output: "out",
+//	})
+//
+// Gen, Bind and Load must not be called concurrently.
+package build
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-python/gopy/bind"
+	"golang.org/x/tools/go/packages"
+)
+
+// Options configures the generation and the build of bindings.
+type Options struct {
+	// Path is the path of the Go package to bind, resolved from the current
+	// directory like the go command does.
+	Path string
+
+	// Output is the directory where the bindings are written.
+	// It defaults to the current directory.
+	Output string
+
+	// Lang is the target language: "python2" (or "py2"), "python3" (or
+	// "py3"), "python" (or "py") for the version of the python interpreter,
+	// "c" for a plain C API, "cffi" for a pure-python module calling the C
+	// API with cffi (e.g. for PyPy), "shim" for a pure-python layer over
+	// an extension module _<pkg>, "go" (Gen only) for the cgo package
+	// wrapping the Go package, or "cython" (Gen only) for the Cython
+	// declarations of that cgo package, or the name of another generator
+	// registered with bind.RegisterGenerator.
+	// It defaults to "python".
+	Lang string
+
+	// Naming is the naming convention of the python names: "go" to keep
+	// the Go names, or "pep8" for snake_case functions, methods, fields and
+	// variables, and UPPER_CASE constants.
+	// It defaults to the naming of the configuration, or "go".
+	Naming string
+
+	// Config is the path of the binding configuration of the package.
+	// It defaults to the gopy.json file of the package directory, if any.
+	Config string
+
+	// Strict makes Gen and Bind fail on declarations which can not be
+	// bound, instead of skipping them.
+	Strict bool
+
+	// Python is the python interpreter to build the bindings for.
+	// By default, the bindings are built against the python found by
+	// pkg-config.
+	Python string
+
+	// Go is the go command used to load and build the packages.
+	// It defaults to "go".
+	Go string
+
+	Tags     string // comma-separated list of build tags
+	LdFlags  string // arguments to pass on each go tool link invocation
+	GcFlags  string // arguments to pass on each go tool compile invocation
+	Race     bool   // enable data race detection
+	TrimPath bool   // remove all file system paths from the resulting library
+
+	// Work keeps the temporary work directory of Bind.
+	// When the main module is vendored, the work directory is created in
+	// the directory of the main module.
+	Work bool
+
+	// Force makes Bind rebuild the bindings instead of using the build cache.
+	Force bool
+
+	// Stdout and Stderr receive the output of the commands run by gopy.
+	// They default to os.Stdout and os.Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Result describes the outcome of Gen and Bind.
+type Result struct {
+	Package  *bind.Package  // the bound package
+	Files    []string       // paths of the files written to the output directory
+	Warnings bind.ErrorList // declarations which can not be bound, and build cache failures
+	WorkDir  string         // work directory, when kept by Options.Work
+	Cached   bool           // whether the library comes from the build cache
+}
+
+// Load loads the package at opts.Path and collects the declarations which can
+// be bound.
+func Load(ctx context.Context, opts Options) (*bind.Package, error) {
+	_, pkg, err := load(token.NewFileSet(), opts, newGoTool(ctx, &opts))
+	return pkg, err
+}
+
+// Gen generates the bindings of the package at opts.Path for opts.Lang, in
+// opts.Output.
+// The packages listed by the binding configuration of the package are
+// generated alongside it.
+func Gen(ctx context.Context, opts Options) (Result, error) {
+	return withPackages(ctx, opts, genOne)
+}
+
+func genOne(ctx context.Context, opts Options) (Result, error) {
+	var res Result
+
+	odir, err := outputDir(opts.Output)
+	if err != nil {
+		return res, err
+	}
+
+	pycfg, err := pythonConfig(opts)
+	if err != nil {
+		return res, err
+	}
+
+	g := newGoTool(ctx, &opts)
+	fset := token.NewFileSet()
+	_, pkg, err := load(fset, opts, g)
+	if err != nil {
+		return res, err
+	}
+	res.Package = pkg
+	res.Warnings = pkg.Warnings()
+	if opts.Strict && len(res.Warnings) > 0 {
+		return res, res.Warnings
+	}
+
+	res.Files, err = genPkg(fset, odir, pkg, targetLang(opts), g, pycfg)
+	return res, err
+}
+
+// Bind generates and compiles the bindings of the package at opts.Path for
+// opts.Lang, and writes the resulting python extension module in
+// opts.Output.
+// For the "c" language, Bind writes the shared library lib<pkg>.so and its C
+// header lib<pkg>.h instead, for the "cffi" language the shared library
+// and the pure-python module <pkg>.py calling it, and for the "shim" language
+// the extension module _<pkg> and the pure-python module <pkg>.py wrapping it.
+// Bind fails for the languages whose generator does not implement
+// bind.Binder (e.g. "go" and "cython"), whose bindings can only be generated.
+// The packages listed by the binding configuration of the package are bound
+// alongside it, each in its own module.
+func Bind(ctx context.Context, opts Options) (Result, error) {
+	return withPackages(ctx, opts, bindOne)
+}
+
+func bindOne(ctx context.Context, opts Options) (Result, error) {
+	var res Result
+
+	odir, err := outputDir(opts.Output)
+	if err != nil {
+		return res, err
+	}
+
+	pycfg, err := pythonConfig(opts)
+	if err != nil {
+		return res, err
+	}
+	ext := ".so"
+	if pycfg != nil {
+		ext = pycfg.ExtSuffix
+	}
+
+	lang := targetLang(opts)
+	gen, err := lookupGenerator(lang, pycfg)
+	if err != nil {
+		return res, err
+	}
+	binder, ok := gen.(bind.Binder)
+	if !ok {
+		return res, fmt.Errorf("gopy: the %q bindings can only be generated (with gopy gen), not built", lang)
+	}
+
+	g := newGoTool(ctx, &opts)
+	fset := token.NewFileSet()
+	gopkg, pkg, err := load(fset, opts, g)
+	if err != nil {
+		return res, err
+	}
+	res.Package = pkg
+	res.Warnings = pkg.Warnings()
+	if opts.Strict && len(res.Warnings) > 0 {
+		return res, res.Warnings
+	}
+
+	// make sure it compiles correctly
+	cmd, err := g.command(append(
+		append([]string{"build"}, g.buildFlags()...),
+		pkg.ImportPath(),
+	)...)
+	if err != nil {
+		return res, err
+	}
+	err = cmd.Run()
+	if err != nil {
+		return res, err
+	}
+
+	plan := binder.BindPlan(pkg, ext)
+	libs := append([]string{plan.Lib}, plan.Files...)
+	key, err := bindKey(gopkg, g, lang, pkg.Naming(), pkg.Config(), pycfg)
+	if err != nil {
+		return res, fmt.Errorf("gopy: could not compute build cache key: %v", err)
+	}
+	if !opts.Force {
+		files, err := cacheGet(key, odir, libs)
+		if err != nil {
+			return res, err
+		}
+		if files != nil {
+			res.Files = files
+			res.Cached = true
+			return res, nil
+		}
+	}
+
+	// when the main module is vendored, the cgo package is built inside it,
+	// from its vendor directory.
+	var vendored string
+	if gopkg.Module != nil {
+		vendored, err = vendoredModule(g)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	work, err := ioutil.TempDir("", "gopy-")
+	if vendored != "" {
+		work, err = ioutil.TempDir(vendored, "_gopy-")
+	}
+	if err != nil {
+		return res, fmt.Errorf("gopy: could not create temp-workdir (%v)", err)
+	}
+	if opts.Work {
+		res.WorkDir = work
+	} else {
+		defer os.RemoveAll(work)
+	}
+
+	wbind, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		return res, fmt.Errorf("gopy: could not create temp-workdir (%v)", err)
+	}
+	defer os.RemoveAll(wbind)
+
+	// the cgo package built as the shared library, and the files written
+	// next to it.
+	for _, name := range plan.Gen {
+		_, err = genPkg(fset, work, pkg, name, g, pycfg)
+		if err != nil {
+			return res, err
+		}
+	}
+	for _, name := range plan.Extra {
+		_, err = genPkg(fset, wbind, pkg, name, g, pycfg)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	buildArgs := []string{"build", "-buildmode=c-shared"}
+	switch {
+	case vendored != "":
+		buildArgs = append(buildArgs, "-mod=vendor")
+	case gopkg.Module != nil:
+		// build the cgo package in a temporary module requiring the module
+		// of the wrapped package.
+		err = genBuildModule(work, gopkg, g)
+		if err != nil {
+			return res, err
+		}
+		buildArgs = append(buildArgs, "-mod=mod")
+	}
+	buildArgs = append(buildArgs, g.buildFlags()...)
+	buildArgs = append(buildArgs, "-o", filepath.Join(wbind, libs[0]), ".")
+
+	cmd, err = g.command(buildArgs...)
+	if err != nil {
+		return res, err
+	}
+	cmd.Dir = work
+	err = cmd.Run()
+	if err != nil {
+		return res, err
+	}
+
+	for _, lib := range libs {
+		// the bindings are built: failing to cache them is not fatal.
+		err = cachePut(key, filepath.Join(wbind, lib))
+		if err != nil {
+			res.Warnings = append(res.Warnings,
+				fmt.Errorf("gopy: could not store bindings in build cache: %v", err),
+			)
+		}
+
+		out := filepath.Join(odir, lib)
+		err = copyFile(out, filepath.Join(wbind, lib))
+		if err != nil {
+			return res, err
+		}
+		res.Files = append(res.Files, out)
+	}
+
+	return res, nil
+}
+
+// withPackages runs build for the package of opts, then for the packages
+// listed by its binding configuration (and by theirs), each with its own
+// configuration.
+// The files and the warnings of these packages are added to the result of
+// the package of opts.
+func withPackages(ctx context.Context, opts Options, build func(context.Context, Options) (Result, error)) (Result, error) {
+	res, err := build(ctx, opts)
+	if err != nil {
+		return res, err
+	}
+
+	seen := map[string]bool{res.Package.ImportPath(): true}
+	paths := append([]string(nil), res.Package.Config().Packages...)
+	for len(paths) > 0 {
+		sub := opts
+		sub.Path, sub.Config = paths[0], ""
+		paths = paths[1:]
+
+		r, err := build(ctx, sub)
+		if r.Package != nil && seen[r.Package.ImportPath()] {
+			continue
+		}
+		res.Warnings = append(res.Warnings, r.Warnings...)
+		if err != nil {
+			return res, fmt.Errorf("gopy: package %s: %v", sub.Path, err)
+		}
+		seen[r.Package.ImportPath()] = true
+		res.Files = append(res.Files, r.Files...)
+		paths = append(paths, r.Package.Config().Packages...)
+	}
+	return res, nil
+}
+
+// load loads the package at opts.Path and collects the declarations which
+// can be bound, as configured by opts.Config and named after opts.Naming.
+func load(fset *token.FileSet, opts Options, g *goTool) (*packages.Package, *bind.Package, error) {
+	naming, err := bind.ParseNaming(opts.Naming)
+	if err != nil {
+		return nil, nil, err
+	}
+	gopkg, err := loadPackage(fset, opts.Path, g)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gopy: could not load package with path=%q: %v", opts.Path, err)
+	}
+	cfg, err := loadConfig(opts.Config, gopkg)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkg, err := newPackageFrom(fset, gopkg, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gopy: could not process package with path=%q: %v", opts.Path, err)
+	}
+	if opts.Naming != "" {
+		pkg.SetNaming(naming)
+	}
+	return gopkg, pkg, nil
+}
+
+// loadConfig reads the binding configuration at path or, when path is empty,
+// the gopy.json file of the directory of pkg.
+// It returns a nil configuration when there is none.
+func loadConfig(path string, pkg *packages.Package) (*bind.Config, error) {
+	if path == "" {
+		if len(pkg.GoFiles) == 0 {
+			return nil, nil
+		}
+		path = filepath.Join(filepath.Dir(pkg.GoFiles[0]), bind.ConfigFile)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("gopy: could not open configuration: %v", err)
+	}
+	defer f.Close()
+	cfg, err := bind.ReadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("gopy: could not read configuration %s: %v", path, err)
+	}
+
+	// the relative paths of the packages bound alongside are resolved from
+	// the directory of the configuration.
+	for i, p := range cfg.Packages {
+		if strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") {
+			cfg.Packages[i], err = filepath.Abs(filepath.Join(filepath.Dir(path), p))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// outputDir creates (if needed) the output directory odir and returns its
+// absolute path.
+func outputDir(odir string) (string, error) {
+	if odir == "" {
+		return os.Getwd()
+	}
+	err := os.MkdirAll(odir, 0755)
+	if err != nil {
+		return "", fmt.Errorf("gopy: could not create output directory: %v", err)
+	}
+	return filepath.Abs(odir)
+}
+
+// targetLang returns the target language of opts.
+func targetLang(opts Options) string {
+	if opts.Lang == "" {
+		return "python"
+	}
+	return opts.Lang
+}
+
+// pythonConfig returns the build configuration of opts.Python, or nil to use
+// pkg-config.
+func pythonConfig(opts Options) (*bind.PyConfig, error) {
+	if opts.Python == "" {
+		return nil, nil
+	}
+	pycfg, err := getPythonConfig(opts.Python)
+	if err != nil {
+		return nil, err
+	}
+
+	vers := 0
+	switch opts.Lang {
+	case "python2", "py2":
+		vers = 2
+	case "python3", "py3":
+		vers = 3
+	}
+	if vers != 0 && vers != pycfg.Version {
+		return nil, fmt.Errorf(
+			"gopy: language %q does not match the version of %s (python%d)",
+			opts.Lang, opts.Python, pycfg.Version,
+		)
+	}
+	return pycfg, nil
+}
@@ -0,0 +1,390 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-python/gopy/bind"
+)
+
+func TestGen(t *testing.T) {
+	odir, err := ioutil.TempDir("", "gopy-build-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(odir)
+
+	opts := Options{
+		Path:   "../_examples/unsupported",
+		Output: odir,
+		Lang:   "py2",
+	}
+	res, err := Gen(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("error generating bindings: %v", err)
+	}
+
+	if got, want := res.Package.Name(), "unsupported"; got != want {
+		t.Errorf("invalid package name: got %q, want %q", got, want)
+	}
+	if got, want := res.Files, []string{filepath.Join(odir, "unsupported.c")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid generated files:\ngot:  %q\nwant: %q", got, want)
+	}
+	for _, fname := range res.Files {
+		_, err = os.Stat(fname)
+		if err != nil {
+			t.Errorf("generated file %s not written: %v", fname, err)
+		}
+	}
+	if got, want := len(res.Warnings), 8; got != want {
+		t.Errorf("got %d warnings, want %d:\n%v", got, want, res.Warnings)
+	}
+
+	opts.Strict = true
+	opts.Output = filepath.Join(odir, "strict")
+	res, err = Gen(context.Background(), opts)
+	if err == nil {
+		t.Fatalf("expected an error in strict mode")
+	}
+	if len(res.Files) != 0 {
+		t.Errorf("unexpected files generated in strict mode: %q", res.Files)
+	}
+}
+
+func TestBindGenOnly(t *testing.T) {
+	odir, err := ioutil.TempDir("", "gopy-build-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(odir)
+
+	for _, lang := range []string{"go", "cython", "py2low"} {
+		res, err := Bind(context.Background(), Options{
+			Path:   "../_examples/simple",
+			Output: odir,
+			Lang:   lang,
+		})
+		want := fmt.Sprintf("gopy: the %q bindings can only be generated (with gopy gen), not built", lang)
+		if err == nil || err.Error() != want {
+			t.Errorf("-lang=%s: got error %v, want %q", lang, err, want)
+		}
+		if len(res.Files) != 0 {
+			t.Errorf("-lang=%s: unexpected files: %q", lang, res.Files)
+		}
+	}
+}
+
+func TestGenNoPkgConfig(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("go command not found: %v", err)
+	}
+	odir, err := ioutil.TempDir("", "gopy-build-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(odir)
+
+	// pkg-config can not be found in a $PATH only holding the go command.
+	err = os.Symlink(gobin, filepath.Join(odir, "go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	os.Setenv("PATH", odir)
+
+	_, err = Gen(context.Background(), Options{
+		Path:   "../_examples/simple",
+		Output: odir,
+		Lang:   "go",
+	})
+	if err == nil || !strings.Contains(err.Error(), "could not locate 'pkg-config' executable") {
+		t.Fatalf("got error %v, want a missing pkg-config error", err)
+	}
+}
+
+func TestGenPackages(t *testing.T) {
+	odir, err := ioutil.TempDir("", "gopy-build-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(odir)
+
+	res, err := Gen(context.Background(), Options{
+		Path:   "../_examples/config",
+		Output: odir,
+		Lang:   "py2",
+	})
+	if err != nil {
+		t.Fatalf("error generating bindings: %v", err)
+	}
+	if got, want := res.Package.Name(), "config"; got != want {
+		t.Errorf("invalid package name: got %q, want %q", got, want)
+	}
+	want := []string{
+		filepath.Join(odir, "config.c"),
+		filepath.Join(odir, "simple.c"),
+	}
+	if got := res.Files; !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid generated files:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	pkg, err := Load(context.Background(), Options{Path: "../_examples/hi"})
+	if err != nil {
+		t.Fatalf("error loading package: %v", err)
+	}
+	if got, want := pkg.ImportPath(), "github.com/go-python/gopy/_examples/hi"; got != want {
+		t.Errorf("invalid import path: got %q, want %q", got, want)
+	}
+
+	_, err = Load(context.Background(), Options{Path: "../_examples/nosuchpkg"})
+	if err == nil {
+		t.Fatalf("expected an error loading a missing package")
+	}
+
+	pkg, err = Load(context.Background(), Options{Path: "../_examples/hi", Naming: "pep8"})
+	if err != nil {
+		t.Fatalf("error loading package: %v", err)
+	}
+	if got, want := pkg.Naming(), bind.PEP8Naming; got != want {
+		t.Errorf("invalid naming: got %v, want %v", got, want)
+	}
+
+	_, err = Load(context.Background(), Options{Path: "../_examples/hi", Naming: "camel"})
+	if err == nil {
+		t.Fatalf("expected an error for an unknown naming")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	pkg, err := Load(context.Background(), Options{Path: "../_examples/config"})
+	if err != nil {
+		t.Fatalf("error loading package: %v", err)
+	}
+	if got, want := pkg.Naming(), bind.PEP8Naming; got != want {
+		t.Errorf("invalid naming from gopy.json: got %v, want %v", got, want)
+	}
+	for _, d := range pkg.Decls() {
+		if d.Name == "Describe" && d.Status != bind.Hidden {
+			t.Errorf("invalid status of Describe: got %v, want %v", d.Status, bind.Hidden)
+		}
+	}
+
+	pkg, err = Load(context.Background(), Options{Path: "../_examples/config", Naming: "go"})
+	if err != nil {
+		t.Fatalf("error loading package: %v", err)
+	}
+	if got, want := pkg.Naming(), bind.GoNaming; got != want {
+		t.Errorf("invalid naming overriding gopy.json: got %v, want %v", got, want)
+	}
+
+	dir, err := ioutil.TempDir("", "gopy-build-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		name string
+		cfg  string
+	}{
+		{"unknown field", `{"exclude": ["Hi"], "rename": {}}`},
+		{"unknown declaration", `{"exclude": ["NoSuchFunc"]}`},
+		{"invalid name", `{"names": {"Hi": "class"}}`},
+		{"invalid gil", `{"gil": {"Hi": "drop"}}`},
+		{"invalid ctor", `{"ctors": {"Person": ["Hi"]}}`},
+	} {
+		fname := filepath.Join(dir, "gopy.json")
+		err := ioutil.WriteFile(fname, []byte(tc.cfg), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Load(context.Background(), Options{Path: "../_examples/hi", Config: fname})
+		if err == nil {
+			t.Errorf("%s: expected an error loading %s", tc.name, tc.cfg)
+		}
+	}
+}
+
+func TestLoadDirectives(t *testing.T) {
+	pkg, err := Load(context.Background(), Options{Path: "../_examples/directives"})
+	if err != nil {
+		t.Fatalf("error loading package: %v", err)
+	}
+	status := make(map[string]bind.Status)
+	for _, d := range pkg.Decls() {
+		status[d.Name] = d.Status
+	}
+	for name, want := range map[string]bind.Status{
+		"Limit":          bind.Bound,
+		"Verbose":        bind.Hidden,
+		"Rect.Cache":     bind.Hidden,
+		"Rect.Debug":     bind.Hidden,
+		"Reveal":         bind.Hidden,
+		"//gopy:unknown": bind.Skipped,
+	} {
+		if got := status[name]; got != want {
+			t.Errorf("invalid status of %s: got %v, want %v", name, got, want)
+		}
+	}
+	if got := len(pkg.Warnings()); got != 1 {
+		t.Errorf("invalid number of warnings: got %d, want 1:\n%v", got, pkg.Warnings())
+	}
+}
+
+func TestLoadInstances(t *testing.T) {
+	pkg, err := Load(context.Background(), Options{Path: "../_examples/generics"})
+	if err != nil {
+		t.Fatalf("error loading package: %v", err)
+	}
+	reasons := make(map[string]string)
+	for _, d := range pkg.Decls() {
+		reasons[d.Name] = d.Reason
+	}
+	for name, want := range map[string]string{
+		"Stack": "instantiated as StackInt, StackString, StackSliceInt, StackMapStringInt",
+		"Pair":  "instantiated as PairStringFloat64",
+	} {
+		if got := reasons[name]; got != want {
+			t.Errorf("invalid reason for %s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGoToolEnviron(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("go command not found: %v", err)
+	}
+	path := os.Getenv("PATH")
+	g := newGoTool(context.Background(), &Options{Go: gobin})
+	env, err := g.environ()
+	if err != nil {
+		t.Fatalf("error computing the environment of %s: %v", gobin, err)
+	}
+	if got := os.Getenv("PATH"); got != path {
+		t.Errorf("$PATH of the process modified:\ngot:  %s\nwant: %s", got, path)
+	}
+
+	out, err := g.output("env", "GOROOT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(strings.TrimSpace(string(out)), "bin")
+	var got []string
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "PATH=") {
+			got = append(got, kv)
+		}
+	}
+	if want := []string{"PATH=" + bin + string(os.PathListSeparator) + path}; !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid $PATH of the go command:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestLoadNames(t *testing.T) {
+	for _, tc := range []struct {
+		naming string
+		want   map[string]string
+	}{
+		{
+			naming: "go",
+			want: map[string]string{
+				"GetDefaultHost": "python name GetDefaultHost already used by var DefaultHost",
+				"HttpGet":        "",
+				"Server.ToDict":  "",
+			},
+		},
+		{
+			naming: "pep8",
+			want: map[string]string{
+				"GetDefaultHost": "python name get_default_host already used by var DefaultHost",
+				"HttpGet":        "python name http_get already used by func HTTPGet",
+				"Server.ToDict":  "python name to_dict already used by the to_dict method",
+				"Server":         "not bound: ToDict",
+			},
+		},
+	} {
+		pkg, err := Load(context.Background(), Options{Path: "../_examples/naming", Naming: tc.naming})
+		if err != nil {
+			t.Fatalf("error loading package: %v", err)
+		}
+		reasons := make(map[string]string)
+		for _, d := range pkg.Decls() {
+			reasons[d.Name] = d.Reason
+		}
+		for name, want := range tc.want {
+			if got := reasons[name]; got != want {
+				t.Errorf("-naming=%s: invalid reason for %s: got %q, want %q", tc.naming, name, got, want)
+			}
+		}
+	}
+}
+
+// nameGenerator is a generator backend writing the name of the package.
+type nameGenerator struct{}
+
+func (nameGenerator) Doc() string { return "name of the package" }
+func (nameGenerator) Ext() string { return ".txt" }
+
+func (nameGenerator) Generate(w io.Writer, fset *token.FileSet, pkg *bind.Package, cfg *bind.PyConfig) error {
+	_, err := fmt.Fprintf(w, "%s\n", pkg.Name())
+	return err
+}
+
+func TestGenGenerator(t *testing.T) {
+	if _, dup := bind.LookupGenerator("test-name"); !dup {
+		bind.RegisterGenerator("test-name", nameGenerator{})
+	}
+
+	odir, err := ioutil.TempDir("", "gopy-build-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(odir)
+
+	res, err := Gen(context.Background(), Options{
+		Path:   "../_examples/hi",
+		Output: odir,
+		Lang:   "test-name",
+	})
+	if err != nil {
+		t.Fatalf("error generating bindings: %v", err)
+	}
+	fname := filepath.Join(odir, "hi.txt")
+	if got, want := res.Files, []string{fname}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid generated files:\ngot:  %q\nwant: %q", got, want)
+	}
+	buf, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), "hi\n"; got != want {
+		t.Errorf("invalid generated file: got %q, want %q", got, want)
+	}
+
+	_, err = Gen(context.Background(), Options{
+		Path:   "../_examples/hi",
+		Output: odir,
+		Lang:   "no-such-lang",
+	})
+	if err == nil {
+		t.Fatalf("expected an error for an unknown language")
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-python/gopy/bind"
+	"golang.org/x/tools/go/packages"
+)
+
+// CacheDir returns the directory of the gopy build cache: $GOPYCACHE if set,
+// or a gopy directory in the user cache directory.
+func CacheDir() (string, error) {
+	if dir := os.Getenv("GOPYCACHE"); dir != "" {
+		return filepath.Abs(dir)
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("gopy: could not locate the build cache (%v)", err)
+	}
+	return filepath.Join(dir, "gopy"), nil
+}
+
+// bindKey returns the key of the bindings of pkg in the build cache.
+// The key covers the gopy executable, the go toolchain and environment, the
+// target python, the naming, the binding configuration, the build flags, the
+// export data of pkg and of its dependencies and the sources of pkg (for the
+// documentation).
+func bindKey(pkg *packages.Package, g *goTool, lang string, naming bind.Naming, cfg *bind.Config, py *bind.PyConfig) (string, error) {
+	h := sha256.New()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	err = hashFile(h, "gopy", exe)
+	if err != nil {
+		return "", err
+	}
+
+	env, err := g.output("env", "GOVERSION", "GOOS", "GOARCH", "CC", "CGO_CFLAGS", "CGO_LDFLAGS")
+	if err != nil {
+		return "", fmt.Errorf("gopy: could not run 'go env': %v", err)
+	}
+	fmt.Fprintf(h, "env %q\n", env)
+	fmt.Fprintf(h, "PKG_CONFIG_PATH %q\n", os.Getenv("PKG_CONFIG_PATH"))
+	fmt.Fprintf(h, "flags %q\n", g.buildFlags())
+	fmt.Fprintf(h, "lang %q\n", lang)
+	fmt.Fprintf(h, "naming %q\n", naming)
+	bcfg, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "config %s\n", bcfg)
+	pycfg, err := json.Marshal(py)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "python %s\n", pycfg)
+
+	args := []string{
+		"list", "-deps", "-export",
+		"-f", "{{if not .Standard}}{{.ImportPath}} {{.Export}}{{end}}",
+	}
+	args = append(args, g.buildFlags()...)
+	out, err := g.output(append(args, pkg.PkgPath)...)
+	if err != nil {
+		return "", fmt.Errorf("gopy: could not list dependencies of %s: %v", pkg.PkgPath, err)
+	}
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		err = hashFile(h, "export "+fields[0], fields[1])
+		if err != nil {
+			return "", err
+		}
+	}
+	if err = s.Err(); err != nil {
+		return "", err
+	}
+
+	for _, fname := range pkg.GoFiles {
+		err = hashFile(h, "file "+filepath.Base(fname), fname)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// hashFile writes to h the name and the content of the file fname.
+func hashFile(h io.Writer, name, fname string) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintf(h, "%s %q\n", name, filepath.Base(fname))
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// cachePath returns the path of the file named name in the cache entry key.
+func cachePath(key, name string) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key[:2], key, name), nil
+}
+
+// cacheGet copies the files named names of the cache entry key to odir, and
+// returns their paths.
+// cacheGet returns no paths if one of the files is not in the cache.
+func cacheGet(key, odir string, names []string) ([]string, error) {
+	var srcs []string
+	for _, name := range names {
+		src, err := cachePath(key, name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(src); err != nil {
+			return nil, nil
+		}
+		srcs = append(srcs, src)
+	}
+
+	var files []string
+	for i, src := range srcs {
+		dst := filepath.Join(odir, names[i])
+		err := copyFile(dst, src)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, dst)
+	}
+	return files, nil
+}
+
+// cachePut stores the file src in the cache entry key.
+func cachePut(key, src string) error {
+	dst, err := cachePath(key, filepath.Base(src))
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(dst), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Close()
+
+	err = copyFile(tmp.Name(), src)
+	if err != nil {
+		return err
+	}
+	// rename is atomic, so concurrent builds never see a partial entry.
+	return os.Rename(tmp.Name(), dst)
+}
+
+// copyFile copies the file src to dst.
+func copyFile(dst, src string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	fi, err := r.Stat()
+	if err != nil {
+		return err
+	}
+
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode())
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
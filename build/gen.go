@@ -0,0 +1,187 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-python/gopy/bind"
+	"golang.org/x/tools/go/packages"
+)
+
+// genPkg generates in odir the bindings of p for lang, and returns the paths
+// of the generated files.
+// The bindings are built against the python described by py or, if py is
+// nil, against the python found by pkg-config.
+func genPkg(fset *token.FileSet, odir string, p *bind.Package, lang string, g *goTool, py *bind.PyConfig) ([]string, error) {
+	gen, err := lookupGenerator(lang, py)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := os.Create(filepath.Join(odir, p.Name()+gen.Ext()))
+	if err != nil {
+		return nil, err
+	}
+	defer o.Close()
+
+	err = gen.Generate(o, fset, p, py)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.Close()
+	if err != nil {
+		return nil, err
+	}
+	files := []string{o.Name()}
+
+	if strings.HasSuffix(o.Name(), ".go") {
+		// the C header of the functions exported by the cgo package.
+		hdr, err := genCgoHeader(o.Name(), g)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, hdr)
+	}
+
+	return files, nil
+}
+
+// lookupGenerator returns the generator of the bindings for lang.
+// The "python" language is the version of py or, if py is nil, of the python
+// interpreter.
+func lookupGenerator(lang string, py *bind.PyConfig) (bind.Generator, error) {
+	var err error
+
+	switch lang {
+	case "python", "py":
+		if py != nil {
+			lang = fmt.Sprintf("py%d", py.Version)
+			break
+		}
+		lang, err = getPythonVersion()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch lang {
+	case "python2":
+		lang = "py2"
+	case "python3", "py3":
+		return nil, fmt.Errorf("gopy: python-3 support not yet implemented")
+	}
+
+	gen, ok := bind.LookupGenerator(lang)
+	if !ok {
+		return nil, fmt.Errorf("unknown target language: %q\n", lang)
+	}
+	return gen, nil
+}
+
+// genCgoHeader generates, next to the cgo package file fname, the C header
+// declaring its exported functions, and returns its path.
+func genCgoHeader(fname string, g *goTool) (string, error) {
+	tmpdir, err := ioutil.TempDir("", "gopy-go-cgo-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	hdr := strings.TrimSuffix(fname, ".go") + ".h"
+	cmd, err := g.command(
+		"tool", "cgo",
+		"-exportheader", hdr,
+		fname,
+	)
+	if err != nil {
+		return "", err
+	}
+	cmd.Dir = tmpdir
+	err = cmd.Run()
+	if err != nil {
+		return "", err
+	}
+	return hdr, nil
+}
+
+func parseFiles(fset *token.FileSet, fnames []string) ([]*ast.File, error) {
+	var (
+		files []*ast.File
+		err   error
+	)
+
+	for _, fname := range fnames {
+		file, errf := parser.ParseFile(fset, fname, nil, parser.ParseComments)
+		if errf != nil {
+			err = errf
+		}
+		files = append(files, file)
+	}
+
+	return files, err
+}
+
+// loadPackage loads and type-checks the package at path, resolved from the
+// current directory, with the build flags of g.
+// Inside a module, path is resolved following the replace directives and
+// vendor directory of the main module.
+func loadPackage(fset *token.FileSet, path string, g *goTool) (*packages.Package, error) {
+	env, err := g.environ()
+	if err != nil {
+		return nil, err
+	}
+	conf := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports |
+			packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo |
+			packages.NeedModule,
+		Context:    g.ctx,
+		Fset:       fset,
+		Env:        env,
+		BuildFlags: g.buildFlags(),
+	}
+	pkgs, err := packages.Load(conf, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("gopy: expected one package for %q, got %d", path, len(pkgs))
+	}
+
+	pkg := pkgs[0]
+	if len(pkg.Errors) > 0 {
+		var list bind.ErrorList
+		for _, err := range pkg.Errors {
+			list = append(list, err)
+		}
+		return nil, list
+	}
+	return pkg, nil
+}
+
+func newPackageFrom(fset *token.FileSet, pkg *packages.Package, cfg *bind.Config) (*bind.Package, error) {
+	// the doc strings are extracted from the original source files, rather
+	// than from the syntax trees of the (possibly cgo-processed) compiled files.
+	files, err := parseFiles(fset, pkg.GoFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgdoc, err := doc.NewFromFiles(fset, files, pkg.PkgPath, doc.PreserveAST)
+	if err != nil {
+		return nil, err
+	}
+
+	return bind.NewPackageConfig(fset, pkg.Types, pkgdoc, cfg)
+}
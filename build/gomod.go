@@ -0,0 +1,215 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// pseudoVersion is the version used to require modules which are replaced
+// by a local directory.
+const pseudoVersion = "v0.0.0-00010101000000-000000000000"
+
+// goModFile mirrors the output of 'go mod edit -json'.
+type goModFile struct {
+	Module struct {
+		Path string
+	}
+	Go      string
+	Require []goModVersion
+	Replace []struct {
+		Old goModVersion
+		New goModVersion
+	}
+}
+
+type goModVersion struct {
+	Path    string
+	Version string
+}
+
+// mainModule returns the path to the go.mod file of the main module, or ""
+// when not in module mode.
+func mainModule(g *goTool) (string, error) {
+	out, err := g.output("env", "GOMOD")
+	if err != nil {
+		return "", fmt.Errorf("gopy: could not run 'go env GOMOD': %v", err)
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == os.DevNull {
+		gomod = ""
+	}
+	return gomod, nil
+}
+
+// vendoredModule returns the root directory of the main module when its
+// dependencies are loaded from its vendor directory, or "" otherwise.
+// Like the go command, the vendor directory is used with -mod=vendor, or
+// when -mod is not set and the main module declares go 1.14 or later.
+func vendoredModule(g *goTool) (string, error) {
+	gomod, err := mainModule(g)
+	if err != nil || gomod == "" {
+		return "", err
+	}
+	root := filepath.Dir(gomod)
+	_, err = os.Stat(filepath.Join(root, "vendor", "modules.txt"))
+	switch {
+	case os.IsNotExist(err):
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+
+	out, err := g.output("env", "GOFLAGS")
+	if err != nil {
+		return "", fmt.Errorf("gopy: could not run 'go env GOFLAGS': %v", err)
+	}
+	for _, flag := range strings.Fields(string(out)) {
+		switch flag {
+		case "-mod=vendor":
+			return root, nil
+		case "-mod=mod", "-mod=readonly":
+			return "", nil
+		}
+	}
+
+	mod, err := readGoMod(g, gomod)
+	if err != nil {
+		return "", err
+	}
+	v := strings.SplitN(mod.Go, ".", 3)
+	if len(v) < 2 || v[0] != "1" {
+		return "", nil
+	}
+	minor, err := strconv.Atoi(v[1])
+	if err != nil || minor < 14 {
+		return "", nil
+	}
+	return root, nil
+}
+
+func readGoMod(g *goTool, gomod string) (*goModFile, error) {
+	out, err := g.output("mod", "edit", "-json", gomod)
+	if err != nil {
+		return nil, fmt.Errorf("gopy: could not read %s: %v", gomod, err)
+	}
+	var f goModFile
+	err = json.Unmarshal(out, &f)
+	if err != nil {
+		return nil, fmt.Errorf("gopy: could not decode %s: %v", gomod, err)
+	}
+	return &f, nil
+}
+
+// genBuildModule writes in dir the go.mod (and go.sum) files of a temporary
+// module, to build the cgo package wrapping pkg.
+// The temporary module requires the module of pkg at the version selected
+// when loading pkg, and reproduces the requirements and replace directives
+// of the main module, so the cgo package is built against the same
+// dependencies.
+func genBuildModule(dir string, pkg *packages.Package, g *goTool) error {
+	m := pkg.Module
+	if m == nil {
+		return fmt.Errorf("gopy: package %q is not part of a module", pkg.PkgPath)
+	}
+
+	requires := make(map[string]string)
+	replaces := make(map[string]string)
+	goversion := m.GoVersion
+
+	gomod, err := mainModule(g)
+	if err != nil {
+		return err
+	}
+	if gomod != "" {
+		mod, err := readGoMod(g, gomod)
+		if err != nil {
+			return err
+		}
+		root := filepath.Dir(gomod)
+		if goversion == "" {
+			goversion = mod.Go
+		}
+		for _, r := range mod.Require {
+			requires[r.Path] = r.Version
+		}
+		for _, r := range mod.Replace {
+			old := r.Old.Path
+			if r.Old.Version != "" {
+				old += " " + r.Old.Version
+			}
+			switch {
+			case r.New.Version != "":
+				replaces[old] = r.New.Path + " " + r.New.Version
+			case filepath.IsAbs(r.New.Path):
+				replaces[old] = r.New.Path
+			default:
+				replaces[old] = filepath.Join(root, r.New.Path)
+			}
+		}
+
+		buf, err := ioutil.ReadFile(filepath.Join(root, "go.sum"))
+		switch {
+		case err == nil:
+			err = ioutil.WriteFile(filepath.Join(dir, "go.sum"), buf, 0644)
+			if err != nil {
+				return err
+			}
+		case !os.IsNotExist(err):
+			return err
+		}
+	}
+
+	switch {
+	case m.Main:
+		requires[m.Path] = pseudoVersion
+		replaces[m.Path] = m.Dir
+	case m.Version == "":
+		// replaced by a local directory.
+		requires[m.Path] = pseudoVersion
+	default:
+		requires[m.Path] = m.Version
+	}
+
+	o := new(bytes.Buffer)
+	fmt.Fprintf(o, "// generated by gopy bind. Do not edit.\n\n")
+	fmt.Fprintf(o, "module gopy-bind/%s\n\n", pkg.Name)
+	if goversion != "" {
+		fmt.Fprintf(o, "go %s\n\n", goversion)
+	}
+	fmt.Fprintf(o, "require (\n")
+	for _, path := range sortedKeys(requires) {
+		fmt.Fprintf(o, "\t%s %s\n", path, requires[path])
+	}
+	fmt.Fprintf(o, ")\n")
+	if len(replaces) > 0 {
+		fmt.Fprintf(o, "\nreplace (\n")
+		for _, old := range sortedKeys(replaces) {
+			fmt.Fprintf(o, "\t%s => %s\n", old, replaces[old])
+		}
+		fmt.Fprintf(o, ")\n")
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, "go.mod"), o.Bytes(), 0644)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
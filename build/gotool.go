@@ -0,0 +1,133 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// goTool runs the go command with the build configuration of Options.
+type goTool struct {
+	ctx      context.Context
+	gobin    string // go command to run
+	tags     string // -tags flag
+	ldflags  string // -ldflags flag
+	gcflags  string // -gcflags flag
+	race     bool   // -race flag
+	trimpath bool   // -trimpath flag
+
+	stdout io.Writer
+	stderr io.Writer
+
+	env []string // environment of the go command, computed lazily.
+}
+
+// newGoTool returns the goTool configured by opts.
+func newGoTool(ctx context.Context, opts *Options) *goTool {
+	g := &goTool{
+		ctx:      ctx,
+		gobin:    opts.Go,
+		tags:     opts.Tags,
+		ldflags:  opts.LdFlags,
+		gcflags:  opts.GcFlags,
+		race:     opts.Race,
+		trimpath: opts.TrimPath,
+		stdout:   opts.Stdout,
+		stderr:   opts.Stderr,
+	}
+	if g.gobin == "" {
+		g.gobin = "go"
+	}
+	if g.stdout == nil {
+		g.stdout = os.Stdout
+	}
+	if g.stderr == nil {
+		g.stderr = os.Stderr
+	}
+	return g
+}
+
+// buildFlags returns the flags to pass to the go build and go list commands.
+func (g *goTool) buildFlags() []string {
+	var flags []string
+	if g.tags != "" {
+		flags = append(flags, "-tags="+g.tags)
+	}
+	if g.ldflags != "" {
+		flags = append(flags, "-ldflags="+g.ldflags)
+	}
+	if g.gcflags != "" {
+		flags = append(flags, "-gcflags="+g.gcflags)
+	}
+	if g.race {
+		flags = append(flags, "-race")
+	}
+	if g.trimpath {
+		flags = append(flags, "-trimpath")
+	}
+	return flags
+}
+
+// environ returns the environment of the go command.
+// When a custom go command is used, the bin directory of its GOROOT is put
+// first in the $PATH of that environment, so tools running "go" (like
+// go/packages) use the same toolchain.
+func (g *goTool) environ() ([]string, error) {
+	if g.env != nil {
+		return g.env, nil
+	}
+	if g.gobin == "go" {
+		g.env = os.Environ()
+		return g.env, nil
+	}
+
+	out, err := exec.CommandContext(g.ctx, g.gobin, "env", "GOROOT").Output()
+	if err != nil {
+		return nil, fmt.Errorf("gopy: could not run '%s env GOROOT': %v", g.gobin, err)
+	}
+	bin := filepath.Join(strings.TrimSpace(string(out)), "bin")
+	path := bin
+	if v := os.Getenv("PATH"); v != "" {
+		path += string(os.PathListSeparator) + v
+	}
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "PATH=") {
+			g.env = append(g.env, kv)
+		}
+	}
+	g.env = append(g.env, "PATH="+path)
+	return g.env, nil
+}
+
+// command returns the command running the go tool with the given arguments.
+// The output of the command goes to the writers of Options.
+func (g *goTool) command(args ...string) (*exec.Cmd, error) {
+	env, err := g.environ()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(g.ctx, g.gobin, args...)
+	cmd.Env = env
+	cmd.Stdout = g.stdout
+	cmd.Stderr = g.stderr
+	return cmd, nil
+}
+
+// output runs the go tool with the given arguments and returns its standard
+// output.
+func (g *goTool) output(args ...string) ([]byte, error) {
+	cmd, err := g.command(args...)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = nil
+	return cmd.Output()
+}
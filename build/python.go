@@ -0,0 +1,118 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/go-python/gopy/bind"
+)
+
+// getPythonVersion returns the python version available on this machine
+func getPythonVersion() (string, error) {
+	py, err := exec.LookPath("python")
+	if err != nil {
+		return "", fmt.Errorf(
+			"gopy: could not locate 'python' executable (err: %v)",
+			err,
+		)
+	}
+
+	out, err := exec.Command(py, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf(
+			"gopy: error retrieving python version (err: %v)",
+			err,
+		)
+	}
+
+	vers := ""
+	switch {
+	case bytes.HasPrefix(out, []byte("Python 2")):
+		vers = "py2"
+	case bytes.HasPrefix(out, []byte("Python 3")):
+		vers = "py3"
+	default:
+		return "", fmt.Errorf(
+			"gopy: invalid python version (%s)",
+			string(out),
+		)
+	}
+
+	return vers, nil
+}
+
+// pyConfigScript prints, as JSON, the build configuration of the python
+// interpreter running it.
+const pyConfigScript = `
+import json, sys, sysconfig
+v = sysconfig.get_config_var
+paths = sysconfig.get_paths()
+json.dump({
+    "version": sys.version_info[0],
+    "include": paths["include"],
+    "platinclude": paths["platinclude"],
+    "libdir": v("LIBDIR") or "",
+    "library": "python" + (v("VERSION") or "") + (v("ABIFLAGS") or ""),
+    "libs": (v("LIBS") or "") + " " + (v("SYSLIBS") or ""),
+    "ext_suffix": v("EXT_SUFFIX") or v("SO") or ".so",
+}, sys.stdout)
+`
+
+// getPythonConfig returns the build configuration of the python interpreter
+// py, as reported by its sysconfig module.
+func getPythonConfig(py string) (*bind.PyConfig, error) {
+	bin, err := exec.LookPath(py)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"gopy: could not locate python executable %q (err: %v)",
+			py, err,
+		)
+	}
+
+	out, err := exec.Command(bin, "-c", pyConfigScript).Output()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"gopy: error retrieving build configuration of %s (err: %v)",
+			bin, err,
+		)
+	}
+
+	var raw struct {
+		Version     int    `json:"version"`
+		Include     string `json:"include"`
+		PlatInclude string `json:"platinclude"`
+		LibDir      string `json:"libdir"`
+		Library     string `json:"library"`
+		Libs        string `json:"libs"`
+		ExtSuffix   string `json:"ext_suffix"`
+	}
+	err = json.Unmarshal(out, &raw)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"gopy: invalid build configuration of %s (err: %v)",
+			bin, err,
+		)
+	}
+
+	cfg := &bind.PyConfig{
+		Version:   raw.Version,
+		CFlags:    []string{"-I" + raw.Include},
+		ExtSuffix: raw.ExtSuffix,
+	}
+	if raw.PlatInclude != "" && raw.PlatInclude != raw.Include {
+		cfg.CFlags = append(cfg.CFlags, "-I"+raw.PlatInclude)
+	}
+	if raw.LibDir != "" {
+		cfg.LdFlags = append(cfg.LdFlags, "-L"+raw.LibDir)
+	}
+	cfg.LdFlags = append(cfg.LdFlags, "-l"+raw.Library)
+	cfg.LdFlags = append(cfg.LdFlags, strings.Fields(raw.Libs)...)
+	return cfg, nil
+}
@@ -5,13 +5,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
 
+	"github.com/go-python/gopy/build"
 	"github.com/gonuts/commander"
 	"github.com/gonuts/flag"
 )
@@ -31,14 +29,19 @@ ex:
 		Flag: *flag.NewFlagSet("gopy-bind", flag.ExitOnError),
 	}
 
-	cmd.Flag.String("lang", "py2", "python version to use for bindings (python2|py2|python3|py3)")
+	cmd.Flag.String("lang", "py2", "target language of the bindings (python2|py2|python3|py3|c|cffi|shim)")
 	cmd.Flag.String("output", "", "output directory for bindings")
+	cmd.Flag.String("naming", "", "naming convention of the python names (go|pep8) (default: from the configuration, or go)")
+	cmd.Flag.String("config", "", "binding configuration of the package (default: its gopy.json file, if any)")
+	cmd.Flag.Bool("strict", false, "fail on declarations which can not be bound instead of skipping them")
+	cmd.Flag.Bool("a", false, "force rebuilding the bindings instead of using the build cache")
+	cmd.Flag.String("python", "", "python interpreter to build the bindings for (default: found by pkg-config)")
+	cmd.Flag.Bool("work", false, "print the name of the temporary work directory and do not delete it when exiting")
+	addGoToolFlags(cmd)
 	return cmd
 }
 
 func gopyRunCmdBind(cmdr *commander.Command, args []string) error {
-	var err error
-
 	if len(args) != 1 {
 		log.Printf("expect a fully qualified go package name as argument\n")
 		return fmt.Errorf(
@@ -46,112 +49,56 @@ func gopyRunCmdBind(cmdr *commander.Command, args []string) error {
 		)
 	}
 
-	odir := cmdr.Flag.Lookup("output").Value.Get().(string)
-	lang := cmdr.Flag.Lookup("lang").Value.Get().(string)
-
-	cwd, err := os.Getwd()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	if odir == "" {
-		odir = cwd
-	} else {
-		err = os.MkdirAll(odir, 0755)
-		if err != nil {
-			return fmt.Errorf(
-				"gopy-bind: could not create output directory: %v\n", err,
-			)
-		}
-	}
-	odir, err = filepath.Abs(odir)
-	if err != nil {
-		return err
+	opts := build.Options{
+		Path:   args[0],
+		Output: cmdr.Flag.Lookup("output").Value.Get().(string),
+		Lang:   cmdr.Flag.Lookup("lang").Value.Get().(string),
+		Naming: cmdr.Flag.Lookup("naming").Value.Get().(string),
+		Config: cmdr.Flag.Lookup("config").Value.Get().(string),
+		Strict: cmdr.Flag.Lookup("strict").Value.Get().(bool),
+		Python: cmdr.Flag.Lookup("python").Value.Get().(string),
+		Work:   cmdr.Flag.Lookup("work").Value.Get().(bool),
+		Force:  cmdr.Flag.Lookup("a").Value.Get().(bool),
 	}
+	setGoToolOptions(cmdr, &opts)
 
-	path := args[0]
-	pkg, err := newPackage(path)
-	if err != nil {
-		return fmt.Errorf(
-			"gopy-bind: go/build.Import failed with path=%q: %v\n",
-			path,
-			err,
-		)
+	res, err := build.Bind(context.Background(), opts)
+	logWarnings(res)
+	if res.WorkDir != "" {
+		log.Printf("work: %s\n", res.WorkDir)
 	}
-
-	// go-get it to tickle the GOPATH cache (and make sure it compiles
-	// correctly)
-	cmd := exec.Command(
-		"go", "get", "-buildmode=c-shared",
-		pkg.ImportPath(),
-	)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err = cmd.Run()
 	if err != nil {
 		return err
 	}
-
-	work, err := ioutil.TempDir("", "gopy-")
-	if err != nil {
-		return fmt.Errorf("gopy-bind: could not create temp-workdir (%v)", err)
-	}
-	log.Printf("work: %s\n", work)
-
-	err = os.MkdirAll(work, 0644)
-	if err != nil {
-		return fmt.Errorf("gopy-bind: could not create workdir (%v)", err)
-	}
-	//defer os.RemoveAll(work)
-
-	err = genPkg(work, pkg, lang)
-	if err != nil {
-		return err
-	}
-
-	err = genPkg(work, pkg, "go")
-	if err != nil {
-		return err
-	}
-
-	wbind, err := ioutil.TempDir("", "gopy-")
-	if err != nil {
-		return fmt.Errorf("gopy-bind: could not create temp-workdir (%v)", err)
+	if res.Cached {
+		log.Printf("using cached %s\n", res.Files[0])
 	}
+	return nil
+}
 
-	err = os.MkdirAll(wbind, 0644)
-	if err != nil {
-		return fmt.Errorf("gopy-bind: could not create workdir (%v)", err)
-	}
-	defer os.RemoveAll(wbind)
+// addGoToolFlags declares the flags configuring the go command.
+func addGoToolFlags(cmd *commander.Command) {
+	cmd.Flag.String("go", "go", "go command used to build the bindings")
+	cmd.Flag.String("tags", "", "comma-separated list of build tags")
+	cmd.Flag.String("ldflags", "", "arguments to pass on each go tool link invocation")
+	cmd.Flag.String("gcflags", "", "arguments to pass on each go tool compile invocation")
+	cmd.Flag.Bool("race", false, "enable data race detection")
+	cmd.Flag.Bool("trimpath", false, "remove all file system paths from the resulting library")
+}
 
-	cmd = exec.Command(
-		"go", "build", "-buildmode=c-shared",
-		"-o", filepath.Join(wbind, pkg.Name())+".so",
-		".",
-	)
-	cmd.Dir = work
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err = cmd.Run()
-	if err != nil {
-		return err
-	}
+// setGoToolOptions sets the go command options of opts from the flags of cmd.
+func setGoToolOptions(cmd *commander.Command, opts *build.Options) {
+	opts.Go = cmd.Flag.Lookup("go").Value.Get().(string)
+	opts.Tags = cmd.Flag.Lookup("tags").Value.Get().(string)
+	opts.LdFlags = cmd.Flag.Lookup("ldflags").Value.Get().(string)
+	opts.GcFlags = cmd.Flag.Lookup("gcflags").Value.Get().(string)
+	opts.Race = cmd.Flag.Lookup("race").Value.Get().(bool)
+	opts.TrimPath = cmd.Flag.Lookup("trimpath").Value.Get().(bool)
+}
 
-	cmd = exec.Command(
-		"/bin/cp",
-		filepath.Join(wbind, pkg.Name())+".so",
-		filepath.Join(odir, pkg.Name())+".so",
-	)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err = cmd.Run()
-	if err != nil {
-		return err
+// logWarnings logs the declarations which can not be exposed to python.
+func logWarnings(res build.Result) {
+	for _, err := range res.Warnings {
+		log.Printf("%v\n", err)
 	}
-
-	return err
 }
@@ -0,0 +1,60 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/go-python/gopy/build"
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+func gopyMakeCmdCache() *commander.Command {
+	cmd := &commander.Command{
+		Run:       gopyRunCmdCache,
+		UsageLine: "cache <clean|dir>",
+		Short:     "manage the cache of built bindings",
+		Long: `
+cache manages the cache of bindings built by gopy bind.
+
+The cache is located in $GOPYCACHE, or in a gopy directory of the user cache
+directory when GOPYCACHE is not set.
+
+ex:
+ $ gopy cache dir
+ $ gopy cache clean
+`,
+		Flag: *flag.NewFlagSet("gopy-cache", flag.ExitOnError),
+	}
+	return cmd
+}
+
+func gopyRunCmdCache(cmdr *commander.Command, args []string) error {
+	if len(args) != 1 {
+		log.Printf("expect 'clean' or 'dir' as argument\n")
+		return fmt.Errorf("gopy-cache: expect 'clean' or 'dir' as argument")
+	}
+
+	dir, err := build.CacheDir()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "clean":
+		err = os.RemoveAll(dir)
+		if err != nil {
+			return fmt.Errorf("gopy-cache: could not clean cache: %v", err)
+		}
+	case "dir":
+		fmt.Println(dir)
+	default:
+		return fmt.Errorf("gopy-cache: unknown command %q", args[0])
+	}
+	return nil
+}
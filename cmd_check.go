@@ -0,0 +1,163 @@
+// Copyright 2015 The go-python Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-python/gopy/bind"
+	"github.com/go-python/gopy/build"
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+func gopyMakeCmdCheck() *commander.Command {
+	cmd := &commander.Command{
+		Run:       gopyRunCmdCheck,
+		UsageLine: "check <go-package-name>",
+		Short:     "report which declarations of a Go package can be bound",
+		Long: `
+check reports how the exported declarations of a Go package, and the exported
+fields and methods of its types, would be exposed to (C)Python.
+
+The -tags, -config and -naming flags are those of gopy bind, so that check
+reports what gopy bind exposes with the same flags.
+
+check exits with a non-zero status when a required declaration can not be
+bound. By default, all the package-level declarations are required.
+
+ex:
+ $ gopy check [options] <go-package-name>
+ $ gopy check github.com/go-python/gopy/_examples/hi
+ $ gopy check -require=Add,Job.Wait github.com/go-python/gopy/_examples/unsupported
+`,
+		Flag: *flag.NewFlagSet("gopy-check", flag.ExitOnError),
+	}
+
+	cmd.Flag.Bool("json", false, "print the report as JSON")
+	cmd.Flag.String("require", "", "comma-separated list of declarations which must be bound")
+	cmd.Flag.String("naming", "", "naming convention of the python names (go|pep8) (default: from the configuration, or go)")
+	cmd.Flag.String("config", "", "binding configuration of the package (default: its gopy.json file, if any)")
+	cmd.Flag.String("go", "go", "go command used to load the package")
+	cmd.Flag.String("tags", "", "comma-separated list of build tags")
+	return cmd
+}
+
+// checkEntry is the JSON representation of a bind.Decl.
+type checkEntry struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Pos    string `json:"pos"`
+}
+
+func gopyRunCmdCheck(cmdr *commander.Command, args []string) error {
+	if len(args) != 1 {
+		log.Printf("expect a fully qualified go package name as argument\n")
+		return fmt.Errorf(
+			"gopy-check: expect a fully qualified go package name as argument",
+		)
+	}
+
+	asJSON := cmdr.Flag.Lookup("json").Value.Get().(bool)
+	require := cmdr.Flag.Lookup("require").Value.Get().(string)
+
+	pkg, err := build.Load(context.Background(), build.Options{
+		Path:   args[0],
+		Naming: cmdr.Flag.Lookup("naming").Value.Get().(string),
+		Config: cmdr.Flag.Lookup("config").Value.Get().(string),
+		Go:     cmdr.Flag.Lookup("go").Value.Get().(string),
+		Tags:   cmdr.Flag.Lookup("tags").Value.Get().(string),
+	})
+	if err != nil {
+		return err
+	}
+
+	decls := pkg.Decls()
+	if asJSON {
+		entries := make([]checkEntry, 0, len(decls))
+		for _, d := range decls {
+			entries = append(entries, checkEntry{
+				Kind:   d.Kind,
+				Name:   d.Name,
+				Status: d.Status.String(),
+				Reason: d.Reason,
+				Pos:    d.Pos.String(),
+			})
+		}
+		out, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(os.Stdout, "%s\n", out)
+		if err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(w, "POSITION\tKIND\tNAME\tSTATUS\tREASON\n")
+		for _, d := range decls {
+			fmt.Fprintf(w, "%s:%d\t%s\t%s\t%s\t%s\n",
+				filepath.Base(d.Pos.Filename), d.Pos.Line,
+				d.Kind, d.Name, d.Status, d.Reason,
+			)
+		}
+		err = w.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	return checkRequired(decls, require)
+}
+
+// checkRequired returns an error listing the required declarations which
+// can not be bound.
+// required is a comma-separated list of declaration names. When empty, all
+// the package-level declarations are required.
+func checkRequired(decls []bind.Decl, required string) error {
+	var missing []string
+	if required == "" {
+		for _, d := range decls {
+			if d.Kind == "field" || d.Kind == "method" {
+				continue
+			}
+			if d.Status == bind.Skipped {
+				missing = append(missing, d.Name)
+			}
+		}
+	} else {
+		status := make(map[string]bind.Status, len(decls))
+		for _, d := range decls {
+			status[d.Name] = d.Status
+		}
+		for _, name := range strings.Split(required, ",") {
+			name = strings.TrimSpace(name)
+			st, ok := status[name]
+			switch {
+			case !ok:
+				missing = append(missing, name+" (no such declaration)")
+			case st == bind.Skipped || st == bind.Hidden:
+				missing = append(missing, name)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf(
+			"gopy-check: required declarations can not be bound: %s",
+			strings.Join(missing, ", "),
+		)
+	}
+	return nil
+}
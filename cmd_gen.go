@@ -5,11 +5,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
+	"text/tabwriter"
 
+	"github.com/go-python/gopy/bind"
+	"github.com/go-python/gopy/build"
 	"github.com/gonuts/commander"
 	"github.com/gonuts/flag"
 )
@@ -29,13 +32,20 @@ ex:
 		Flag: *flag.NewFlagSet("gopy-gen", flag.ExitOnError),
 	}
 
-	cmd.Flag.String("lang", "python", "target language for bindings")
+	cmd.Flag.String("lang", "python", "target language for bindings (-lang=list to list them)")
 	cmd.Flag.String("output", "", "output directory for bindings")
+	cmd.Flag.String("naming", "", "naming convention of the python names (go|pep8) (default: from the configuration, or go)")
+	cmd.Flag.String("config", "", "binding configuration of the package (default: its gopy.json file, if any)")
+	cmd.Flag.Bool("strict", false, "fail on declarations which can not be bound instead of skipping them")
+	cmd.Flag.String("python", "", "python interpreter to generate the bindings for (default: found by pkg-config)")
 	return cmd
 }
 
 func gopyRunCmdGen(cmdr *commander.Command, args []string) error {
-	var err error
+	lang := cmdr.Flag.Lookup("lang").Value.Get().(string)
+	if lang == "list" {
+		return listGenerators()
+	}
 
 	if len(args) != 1 {
 		log.Printf("expect a fully qualified go package name as argument\n")
@@ -44,47 +54,27 @@ func gopyRunCmdGen(cmdr *commander.Command, args []string) error {
 		)
 	}
 
-	odir := cmdr.Flag.Lookup("output").Value.Get().(string)
-	lang := cmdr.Flag.Lookup("lang").Value.Get().(string)
-
-	cwd, err := os.Getwd()
-	if err != nil {
-		log.Fatal(err)
+	opts := build.Options{
+		Path:   args[0],
+		Output: cmdr.Flag.Lookup("output").Value.Get().(string),
+		Lang:   lang,
+		Naming: cmdr.Flag.Lookup("naming").Value.Get().(string),
+		Config: cmdr.Flag.Lookup("config").Value.Get().(string),
+		Strict: cmdr.Flag.Lookup("strict").Value.Get().(bool),
+		Python: cmdr.Flag.Lookup("python").Value.Get().(string),
 	}
 
-	if odir == "" {
-		odir = cwd
-	} else {
-		err = os.MkdirAll(odir, 0755)
-		if err != nil {
-			return fmt.Errorf(
-				"gopy-gen: could not create output directory: %v\n", err,
-			)
-		}
-	}
-
-	odir, err = filepath.Abs(odir)
-	if err != nil {
-		return fmt.Errorf(
-			"gopy-gen: could not infer absolute path to output directory: %v\n",
-			err,
-		)
-	}
-
-	path := args[0]
-	pkg, err := newPackage(path)
-	if err != nil {
-		return fmt.Errorf(
-			"gopy-gen: go/build.Inport failed with path=%q: %v\n",
-			path,
-			err,
-		)
-	}
+	res, err := build.Gen(context.Background(), opts)
+	logWarnings(res)
+	return err
+}
 
-	err = genPkg(odir, pkg, lang)
-	if err != nil {
-		return err
+// listGenerators prints the registered generator backends.
+func listGenerators() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, name := range bind.Generators() {
+		gen, _ := bind.LookupGenerator(name)
+		fmt.Fprintf(w, "%s\t%s\n", name, gen.Doc())
 	}
-
-	return err
+	return w.Flush()
 }
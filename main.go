@@ -22,6 +22,8 @@ func init() {
 		Subcommands: []*commander.Command{
 			gopyMakeCmdGen(),
 			gopyMakeCmdBind(),
+			gopyMakeCmdCheck(),
+			gopyMakeCmdCache(),
 		},
 		Flag: *flag.NewFlagSet("gopy", flag.ExitOnError),
 	}
@@ -6,17 +6,36 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
 type pkg struct {
-	path string
-	want []byte
+	path   string
+	flags  []string // additional flags for gopy bind
+	python string   // python interpreter running the test, python2 by default
+	want   []byte
+}
+
+func TestMain(m *testing.M) {
+	// the gopy commands run by the tests never use the build cache of the
+	// user: stale bindings would hide regressions.
+	cache, err := ioutil.TempDir("", "gopy-cache-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not create build cache: %v\n", err)
+		os.Exit(1)
+	}
+	os.Setenv("GOPYCACHE", cache)
+	code := m.Run()
+	os.RemoveAll(cache)
+	os.Exit(code)
 }
 
 func testPkg(t *testing.T, table pkg) {
@@ -30,7 +49,10 @@ func testPkg(t *testing.T, table pkg) {
 	}
 	defer os.RemoveAll(workdir)
 
-	cmd := exec.Command("gopy", "bind", "-output="+workdir, "./"+table.path)
+	args := append([]string{"bind", "-output=" + workdir}, table.flags...)
+	cmd := exec.Command("gopy", append(args, "./"+table.path)...)
+	// each test builds its bindings in its own build cache.
+	cmd.Env = append(os.Environ(), "GOPYCACHE="+filepath.Join(workdir, "cache"))
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -51,8 +73,12 @@ func testPkg(t *testing.T, table pkg) {
 		t.Fatalf("[%s]: error copying 'test.py': %v\n", table.path, err)
 	}
 
+	python := table.python
+	if python == "" {
+		python = "python2"
+	}
 	buf := new(bytes.Buffer)
-	cmd = exec.Command("python2", "./test.py")
+	cmd = exec.Command(python, "./test.py")
 	cmd.Dir = workdir
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = buf
@@ -110,6 +136,8 @@ package hi exposes a few Go functions to be wrapped and used from Python.
 
 --- hi.GetUniverse(): 42
 --- hi.GetVersion(): 0.1
+--- hi.Universe: 42
+--- hi.Version: 0.1
 --- hi.GetDebug(): False
 --- hi.SetDebug(true)
 --- hi.GetDebug(): True
@@ -119,6 +147,15 @@ package hi exposes a few Go functions to be wrapped and used from Python.
 --- new anon: hi.Person{Name="you", Age=24}
 --- hi.SetAnon(hi.NewPerson('you', 24))...
 --- hi.GetAnon(): hi.Person{Name="you", Age=24}
+--- hi.Debug: False
+--- hi.Debug = True
+--- hi.GetDebug(): True
+--- hi.Debug: False
+--- hi.Anon.Age = 25
+--- hi.Anon: hi.Person{Name="you", Age=25}
+--- hi.IntSlice[0] = 3
+--- hi.GetIntSlice(): []int{3, 2}
+--- hi.Anon: hi.Person{Name="you", Age=24}
 --- doc(hi.Hi)...
 Hi() 
 
@@ -145,8 +182,16 @@ Add returns the sum of its arguments.
 --- doc(hi.Person):
 Person is a simple struct
 
+--- doc(hi.Person.Name):
+Name string
+
+Name is the first name of the person.
+--- doc(hi.Person.Age):
+Age int
+
+age in years
 --- p = hi.Person()...
-['Age', 'Greet', 'Name', 'Salary', 'String', 'Work', '__class__', '__delattr__', '__doc__', '__format__', '__getattribute__', '__hash__', '__init__', '__new__', '__reduce__', '__reduce_ex__', '__repr__', '__setattr__', '__sizeof__', '__str__', '__subclasshook__']
+['Age', 'Greet', 'Name', 'Salary', 'String', 'Work', '__class__', '__delattr__', '__doc__', '__format__', '__getattribute__', '__hash__', '__init__', '__new__', '__reduce__', '__reduce_ex__', '__repr__', '__setattr__', '__sizeof__', '__str__', '__subclasshook__', 'from_dict', 'to_dict']
 --- p: hi.Person{Name="", Age=0}
 --- p.Name: 
 --- p.Age: 0
@@ -261,6 +306,8 @@ func TestBindSimple(t *testing.T) {
 pkg.Func()...
 fct = pkg.Func...
 fct()...
+pkg.Answer(1, 'a') = 42
+pkg.Second(1, 2, 3) = 2
 `),
 	})
 }
@@ -277,17 +324,20 @@ doc(pkg):
 }
 
 func TestBindPointers(t *testing.T) {
-	t.Skip("not ready yet")
 	t.Parallel()
 	testPkg(t, pkg{
 		path: "_examples/pointers",
 		want: []byte(`s = pointers.S(2)
-s = pointers.S{Value:2}
 s.Value = 2
 pointers.Inc(s)
-==> go: s.Value==2
-<== go: s.Value==3
 s.Value = 3
+t.Value = 10
+s.Add(t)
+s.Value = 13
+t.Value = 11
+hasattr(pointers, 'IncInt') = False
+hasattr(pointers, 'IncMyInt') = False
+hasattr(pointers, 'Total') = False
 `),
 	})
 }
@@ -380,6 +430,16 @@ caught error: S2.__init__ takes at most 1 argument(s)
 s2 = structs.S2{Public:42, private:0}
 s2.Public = 42
 caught error: 'structs.S2' object has no attribute 'private'
+s3 = structs.S3(id=1, name='x', Public=2)
+s3 = structs.S3{ID:1, Name:"x", Secret:"", Public:2}
+s3.id = 1
+s3.name = x
+s3.to_dict() = [('Public', 2), ('id', 1), ('name', 'x')]
+caught error: attribute 'id' of 'structs.S3' objects is not writable
+caught error: 'structs.S3' object has no attribute 'ID'
+caught error: 'structs.S3' object has no attribute 'Name'
+caught error: 'structs.S3' object has no attribute 'Secret'
+caught error: 'Secret' is an invalid keyword argument for this function
 `),
 	})
 }
@@ -397,6 +457,16 @@ c6 = 42
 c7 = 666.666
 k1 = 1
 k2 = 2
+consts.C1 = 'c1'
+consts.C2 = 42
+consts.C3 = 666.666
+consts.C4 = 'c4'
+consts.C5 = 42
+consts.C6 = 42L
+consts.C7 = 666.666
+consts.Big = 1180591620717411303424L
+consts.Kind1 = 1 (Kind)
+consts.Kind2 = 2
 `),
 	})
 }
@@ -452,3 +522,1050 @@ func TestBindInterfaces(t *testing.T) {
 `),
 	})
 }
+
+func TestBindComplex(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path: "_examples/cmplx",
+		want: []byte(`cmplx.Add(1+2j, 3-1j) = (4+1j)
+cmplx.Add(1, 2.5) = (3.5+0j)
+cmplx.Conj(1+2j) = (1-2j)
+caught: a float is required
+p = (1+2i)
+p.Real() = 1.0
+complex(p) = (1+2j)
+p + 1j = (1+3i)
+2 * p = (2+4i)
+p - p = (0+0i)
+p / 2 = (0.5+1i)
+-p = (-1-2i)
+abs(cmplx.Phasor(3+4j)) = 5.0
+bool(p - p) = False
+type(p * p) = Phasor
+cmplx.Scale(p, 2) = (2+4i)
+cmplx.Scale(3j, 2) = (0+6i)
+caught: complex division by zero
+s = cmplx.Slice{(0+0i), (1-1i), (2-2i)}
+s[1] = (1-1j)
+s[1] = 5j
+mem(s): format=Zd itemsize=16 len=3
+`),
+	})
+}
+
+func TestBindDicts(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path: "_examples/dicts",
+		want: []byte(`p = Bob (42) at Main/1234, temp=36.6 tags=[a b] scores=1 secret=""
+p.to_dict() = {'Temp': 36.6, 'age': 42, 'home': {'street': 'Main', 'zip': 1234}, 'name': 'Bob', 'scores': {'math': 12}, 'tags': ['a', 'b']}
+home = {'street': 'Main', 'zip': 1234}
+types: float list dict
+roundtrip = Bob (42) at Main/1234, temp=36.6 tags=[a b] scores=1 secret=""
+p =  (0) at Elm/7, temp=0 tags=[] scores=0 secret=""
+caught: Person.from_dict: field 'age': expected int, got str
+caught: Person.from_dict: unknown field 'nickname'
+caught: Person.from_dict: unknown field 'Secret'
+caught: Person.from_dict: field 'home.zip': expected int, got str
+caught: Person.from_dict: field 'home': expected a dict or Address, got int
+caught: Person.from_dict: field 'tags': invalid type (got=int, expected a string)
+caught: Person.from_dict: field 'scores': invalid type (got=str, expected a int)
+caught: Person.from_dict: expected a dict, got list
+`),
+	})
+}
+
+func TestBindEnums(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path: "_examples/enums",
+		want: []byte(`members: ['RED', 'GREEN', 'BLUE']
+enums.Red = <Color.RED: 0>
+enums.Color.GREEN = <Color.GREEN: 1>
+enums.Blue is enums.Color.BLUE: True
+enums.Color(2) = Color.BLUE
+enums.Red == 0: True
+isinstance(enums.Green, int): True
+enums.Next(enums.Red) = <Color.GREEN: 1>
+enums.Next(enums.Blue) = <Color.RED: 0>
+enums.Next(1) = <Color.BLUE: 2>
+enums.Name(enums.Blue) = BLUE
+enums.Invalid() = 42
+caught: invalid Color
+perms: ['Read', 'Write', 'Exec']
+enums.Exec = <Perm.Exec: 4>
+enums.Exec.value = 4
+enums.Describe(enums.Read | enums.Exec) = rx
+enums.Has(enums.All(), enums.Write) = True
+int(enums.All()) = 7
+enums.All() = <Perm.Read|Write|Exec: 7>
+enums.Perm(5).name = Read|Exec
+`),
+	})
+}
+
+func TestBindUnsupported(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path: "_examples/unsupported",
+		want: []byte(`unsupported.Add(1, 2) = 3
+unsupported.Count = 42
+hasattr(unsupported, 'Events') = False
+hasattr(unsupported, 'Pipe') = False
+hasattr(unsupported, 'Registry') = False
+hasattr(unsupported, 'Send') = False
+hasattr(unsupported, 'Split') = False
+j = build
+j.Name = build, j.ID = 3
+hasattr(j, 'Done') = False
+hasattr(j, 'Wait') = False
+`),
+	})
+}
+
+func TestBindStrict(t *testing.T) {
+	t.Parallel()
+	workdir, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		t.Fatalf("could not create workdir: %v\n", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	buf := new(bytes.Buffer)
+	cmd := exec.Command("gopy", "gen", "-strict", "-output="+workdir, "./_examples/unsupported")
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	err = cmd.Run()
+	if err == nil {
+		t.Fatalf("expected gopy-gen -strict to fail\n%s\n", buf.String())
+	}
+
+	for _, want := range []string{
+		"unsupported.go:10:5: skipping Events: unsupported type chan int",
+		"unsupported.go:16:6: skipping Pipe: unsupported type unsupported.Pipe (chan string)",
+		"unsupported.go:19:6: skipping Registry: unsupported type unsupported.Registry (map[string]int)",
+		"unsupported.go:22:6: skipping Send: unsupported type chan int",
+		"unsupported.go:27:6: skipping Split: too many results (3)",
+		"unsupported.go:39:2: skipping field Job.Done: unsupported type chan bool",
+		"unsupported.go:49:15: skipping method Job.Wait: unsupported type <-chan bool",
+		"unsupported.go:61:2: skipping method Waiter.Wait: unsupported type <-chan bool",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("missing diagnostic %q in:\n%s\n", want, buf.String())
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	stdout := new(bytes.Buffer)
+	cmd := exec.Command("gopy", "check", "./_examples/unsupported")
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err == nil {
+		t.Fatalf("expected gopy-check to fail on unbindable declarations\n")
+	}
+
+	want := `POSITION           KIND    NAME         STATUS   REASON
+unsupported.go:10  var     Events       skipped  unsupported type chan int
+unsupported.go:13  var     Count        bound    
+unsupported.go:16  type    Pipe         skipped  unsupported type unsupported.Pipe (chan string)
+unsupported.go:19  type    Registry     skipped  unsupported type unsupported.Registry (map[string]int)
+unsupported.go:22  func    Send         skipped  unsupported type chan int
+unsupported.go:27  func    Split        skipped  too many results (3)
+unsupported.go:32  func    Add          bound    
+unsupported.go:37  type    Job          partial  not bound: Done, Wait
+unsupported.go:38  field   Job.Name     bound    
+unsupported.go:39  field   Job.Done     skipped  unsupported type chan bool
+unsupported.go:40  field   Job.ID       bound    
+unsupported.go:44  func    NewJob       bound    
+unsupported.go:49  method  Job.Wait     skipped  unsupported type <-chan bool
+unsupported.go:54  method  Job.String   bound    
+unsupported.go:59  type    Waiter       partial  not bound: Wait
+unsupported.go:61  method  Waiter.Wait  skipped  unsupported type <-chan bool
+`
+	if got := stdout.String(); got != want {
+		t.Fatalf("gopy-check report:\ngot:\n%s\nwant:\n%s\n", got, want)
+	}
+}
+
+func TestCheckJSON(t *testing.T) {
+	t.Parallel()
+	for _, table := range []struct {
+		require string
+		fail    bool
+	}{
+		{require: "Add,Count,Job.Name", fail: false},
+		{require: "Add,Job.Wait", fail: true},
+		{require: "NoSuchFunc", fail: true},
+	} {
+		stdout := new(bytes.Buffer)
+		cmd := exec.Command(
+			"gopy", "check", "-json", "-require="+table.require,
+			"./_examples/unsupported",
+		)
+		cmd.Stdout = stdout
+		cmd.Stderr = ioutil.Discard
+		err := cmd.Run()
+		if (err != nil) != table.fail {
+			t.Errorf("-require=%s: got err=%v, want failure=%v", table.require, err, table.fail)
+		}
+
+		var entries []struct {
+			Kind   string `json:"kind"`
+			Name   string `json:"name"`
+			Status string `json:"status"`
+			Reason string `json:"reason"`
+			Pos    string `json:"pos"`
+		}
+		err = json.Unmarshal(stdout.Bytes(), &entries)
+		if err != nil {
+			t.Fatalf("-require=%s: could not decode JSON report: %v\n%s", table.require, err, stdout.String())
+		}
+		if len(entries) != 16 {
+			t.Fatalf("-require=%s: got %d entries, want 16", table.require, len(entries))
+		}
+		e := entries[12]
+		if e.Kind != "method" || e.Name != "Job.Wait" || e.Status != "skipped" ||
+			e.Reason != "unsupported type <-chan bool" ||
+			!strings.HasSuffix(e.Pos, "unsupported.go:49:15") {
+			t.Errorf("-require=%s: invalid entry for Job.Wait: %+v", table.require, e)
+		}
+	}
+}
+
+func TestCheckFlags(t *testing.T) {
+	t.Parallel()
+	workdir, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		t.Fatalf("could not create workdir: %v\n", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	config := filepath.Join(workdir, "gopy.json")
+	err = ioutil.WriteFile(config, []byte(`{"exclude": ["Add"]}`), 0644)
+	if err != nil {
+		t.Fatalf("could not write configuration: %v\n", err)
+	}
+
+	for _, table := range []struct {
+		path   string
+		flags  []string
+		name   string
+		status string
+	}{
+		{path: "./_examples/buildtags", name: "Extra", status: ""},
+		{path: "./_examples/buildtags", flags: []string{"-tags=gopy_extra"}, name: "Extra", status: "bound"},
+		{path: "./_examples/unsupported", name: "Add", status: "bound"},
+		{path: "./_examples/unsupported", flags: []string{"-config=" + config}, name: "Add", status: "hidden"},
+		{path: "./_examples/naming", flags: []string{"-naming=go"}, name: "HttpGet", status: "bound"},
+		{path: "./_examples/naming", flags: []string{"-naming=pep8"}, name: "HttpGet", status: "skipped"},
+	} {
+		stdout := new(bytes.Buffer)
+		args := append([]string{"check", "-json"}, table.flags...)
+		cmd := exec.Command("gopy", append(args, table.path)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = ioutil.Discard
+		// the exit status only reports the required declarations.
+		_ = cmd.Run()
+
+		var entries []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		}
+		err = json.Unmarshal(stdout.Bytes(), &entries)
+		if err != nil {
+			t.Fatalf("%s %q: could not decode JSON report: %v\n%s", table.path, table.flags, err, stdout.String())
+		}
+		status := ""
+		for _, e := range entries {
+			if e.Name == table.name {
+				status = e.Status
+			}
+		}
+		if status != table.status {
+			t.Errorf("%s %q: invalid status for %s: got %q, want %q", table.path, table.flags, table.name, status, table.status)
+		}
+	}
+}
+
+func TestBindModule(t *testing.T) {
+	t.Parallel()
+	workdir, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		t.Fatalf("could not create workdir: %v\n", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	// a module whose package depends on a module replaced by a local directory.
+	for name, content := range map[string]string{
+		"mod/go.mod": `module example.com/mod
+
+go 1.21
+
+require example.com/dep v1.0.0
+
+replace example.com/dep => ../dep
+`,
+		"mod/calc/calc.go": `package calc
+
+import "example.com/dep"
+
+// Quad returns 4*i.
+func Quad(i int) int {
+	return dep.Twice(dep.Twice(i))
+}
+`,
+		"dep/go.mod": "module example.com/dep\n\ngo 1.21\n",
+		"dep/dep.go": `package dep
+
+func Twice(i int) int {
+	return 2 * i
+}
+`,
+	} {
+		fname := filepath.Join(workdir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(fname), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(fname, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	odir := filepath.Join(workdir, "out")
+	env := append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod", "GOPROXY=off")
+	cmd := exec.Command("gopy", "bind", "-output="+odir, "./calc")
+	cmd.Dir = filepath.Join(workdir, "mod")
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running gopy-bind in a module: %v\n", err)
+	}
+
+	buf := new(bytes.Buffer)
+	cmd = exec.Command("python2", "-c", "import calc; print(calc.Quad(3))")
+	cmd.Dir = odir
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running python module: %v\n%s\n", err, buf.String())
+	}
+	if got, want := buf.String(), "12\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestBindVendor(t *testing.T) {
+	t.Parallel()
+	workdir, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		t.Fatalf("could not create workdir: %v\n", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	// a module whose package depends on a module only found in its vendor
+	// directory.
+	for name, content := range map[string]string{
+		"mod/go.mod": `module example.com/mod
+
+go 1.21
+
+require example.com/dep v1.0.0
+`,
+		"mod/calc/calc.go": `package calc
+
+import "example.com/dep"
+
+// Quad returns 4*i.
+func Quad(i int) int {
+	return dep.Twice(dep.Twice(i))
+}
+`,
+		"mod/vendor/modules.txt": `# example.com/dep v1.0.0
+## explicit; go 1.21
+example.com/dep
+`,
+		"mod/vendor/example.com/dep/dep.go": `package dep
+
+func Twice(i int) int {
+	return 2 * i
+}
+`,
+	} {
+		fname := filepath.Join(workdir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(fname), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(fname, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	odir := filepath.Join(workdir, "out")
+	env := append(os.Environ(), "GO111MODULE=on", "GOFLAGS=", "GOPROXY=off")
+	cmd := exec.Command("gopy", "bind", "-output="+odir, "./calc")
+	cmd.Dir = filepath.Join(workdir, "mod")
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running gopy-bind in a vendored module: %v\n", err)
+	}
+
+	buf := new(bytes.Buffer)
+	cmd = exec.Command("python2", "-c", "import calc; print(calc.Quad(3))")
+	cmd.Dir = odir
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running python module: %v\n%s\n", err, buf.String())
+	}
+	if got, want := buf.String(), "12\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	dirs, err := filepath.Glob(filepath.Join(workdir, "mod", "_gopy-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 0 {
+		t.Fatalf("work directories left in the module: %v", dirs)
+	}
+}
+
+func TestBindGenerics(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path: "_examples/generics",
+		want: []byte(`s.Len() = 3
+generics.Sum(s) = 42
+s.Pop() = 39
+s.Items = [1, 2]
+ss.Pop() = world
+ss.Len() = 1
+doc(StackString.Push) = Push adds v on top of the stack.
+p.Key = pi, p.Value = 3.14
+sl.Len() = 2
+sl.Pop() = [3]
+sm.Len() = 1
+sm.to_dict() = {'Items': [{'a': 1}]}
+hasattr(generics, 'Stack') = False
+hasattr(generics, 'Pair') = False
+hasattr(generics, 'Queue') = False
+hasattr(generics, 'Max') = False
+`),
+	})
+}
+
+func TestBindBuildFlags(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path: "_examples/buildtags",
+		flags: []string{
+			"-tags=gopy_extra",
+			"-ldflags=-X github.com/go-python/gopy/_examples/buildtags.version=1.2.3",
+			"-trimpath",
+		},
+		want: []byte(`buildtags.Version() = 1.2.3
+buildtags.Extra() = extra
+`),
+	})
+}
+
+func TestBindWork(t *testing.T) {
+	t.Parallel()
+	odir, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		t.Fatalf("could not create output dir: %v\n", err)
+	}
+	defer os.RemoveAll(odir)
+
+	for _, keep := range []bool{false, true} {
+		stderr := new(bytes.Buffer)
+		cmd := exec.Command(
+			"gopy", "bind", "-a", "-output="+odir, fmt.Sprintf("-work=%v", keep),
+			"./_examples/hi",
+		)
+		cmd.Stdout = ioutil.Discard
+		cmd.Stderr = stderr
+		err = cmd.Run()
+		if err != nil {
+			t.Fatalf("-work=%v: error running gopy-bind: %v\n%s", keep, err, stderr.String())
+		}
+
+		var work string
+		for _, line := range strings.Split(stderr.String(), "\n") {
+			if i := strings.Index(line, "work: "); i >= 0 {
+				work = strings.TrimSpace(line[i+len("work: "):])
+			}
+		}
+		if !keep {
+			if work != "" {
+				t.Fatalf("-work=false: unexpected work directory %q", work)
+			}
+			continue
+		}
+		if work == "" {
+			t.Fatalf("-work=true: work directory not printed:\n%s", stderr.String())
+		}
+		defer os.RemoveAll(work)
+		_, err = os.Stat(filepath.Join(work, "hi.go"))
+		if err != nil {
+			t.Fatalf("-work=true: work directory not kept: %v", err)
+		}
+	}
+}
+
+func TestBindPython(t *testing.T) {
+	t.Parallel()
+	py, err := exec.LookPath("python2")
+	if err != nil {
+		t.Skipf("python2 not available: %v", err)
+	}
+
+	workdir, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		t.Fatalf("could not create workdir: %v\n", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	cmd := exec.Command(
+		"gopy", "gen", "-lang=go", "-python="+py, "-output="+workdir,
+		"./_examples/hi",
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running gopy-gen: %v\n", err)
+	}
+	src, err := ioutil.ReadFile(filepath.Join(workdir, "hi.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"//#cgo CFLAGS: -I", "//#cgo LDFLAGS: ", " -lpython2.7"} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("generated cgo package does not contain %q", want)
+		}
+	}
+	if bytes.Contains(src, []byte("pkg-config")) {
+		t.Errorf("generated cgo package uses pkg-config")
+	}
+
+	testPkg(t, pkg{
+		path:  "_examples/simple",
+		flags: []string{"-python=" + py},
+		want: []byte(`doc(pkg):
+'simple is a simple package.\n'
+pkg.Func()...
+fct = pkg.Func...
+fct()...
+pkg.Answer(1, 'a') = 42
+pkg.Second(1, 2, 3) = 2
+`),
+	})
+}
+
+func TestBindCache(t *testing.T) {
+	t.Parallel()
+	workdir, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		t.Fatalf("could not create workdir: %v\n", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	cache := filepath.Join(workdir, "cache")
+	odir := filepath.Join(workdir, "out")
+	env := append(os.Environ(), "GOPYCACHE="+cache)
+
+	for i, table := range []struct {
+		flags  []string
+		cached bool
+	}{
+		{flags: nil, cached: false},
+		{flags: nil, cached: true},
+		{flags: []string{"-a"}, cached: false},
+		{flags: []string{"-tags=gopy_cache"}, cached: false},
+		{flags: []string{"-tags=gopy_cache"}, cached: true},
+	} {
+		os.RemoveAll(odir)
+		stderr := new(bytes.Buffer)
+		args := append([]string{"bind", "-output=" + odir}, table.flags...)
+		cmd := exec.Command("gopy", append(args, "./_examples/funcs")...)
+		cmd.Env = env
+		cmd.Stdout = ioutil.Discard
+		cmd.Stderr = stderr
+		err = cmd.Run()
+		if err != nil {
+			t.Fatalf("#%d: error running gopy-bind: %v\n%s", i, err, stderr.String())
+		}
+		if got := strings.Contains(stderr.String(), "using cached "); got != table.cached {
+			t.Fatalf("#%d %q: got cached=%v, want %v\n%s", i, table.flags, got, table.cached, stderr.String())
+		}
+		_, err = os.Stat(filepath.Join(odir, "funcs.so"))
+		if err != nil {
+			t.Fatalf("#%d: bindings not written: %v", i, err)
+		}
+	}
+
+	cmd := exec.Command("gopy", "cache", "clean")
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running gopy-cache: %v\n", err)
+	}
+	_, err = os.Stat(cache)
+	if !os.IsNotExist(err) {
+		t.Fatalf("cache not cleaned: %v", err)
+	}
+
+	// the bindings are still written when they can not be cached.
+	err = ioutil.WriteFile(cache, nil, 0644)
+	if err != nil {
+		t.Fatalf("could not write cache file: %v\n", err)
+	}
+	os.RemoveAll(odir)
+	stderr := new(bytes.Buffer)
+	cmd = exec.Command("gopy", "bind", "-output="+odir, "./_examples/funcs")
+	cmd.Env = env
+	cmd.Stdout = ioutil.Discard
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running gopy-bind with an unusable cache: %v\n%s", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "could not store bindings in build cache") {
+		t.Fatalf("missing build cache warning:\n%s", stderr.String())
+	}
+	_, err = os.Stat(filepath.Join(odir, "funcs.so"))
+	if err != nil {
+		t.Fatalf("bindings not written: %v", err)
+	}
+}
+
+func TestBindC(t *testing.T) {
+	t.Parallel()
+	workdir, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		t.Fatalf("could not create workdir: %v\n", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	cmd := exec.Command("gopy", "bind", "-lang=c", "-output="+workdir, "./_examples/capi")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running gopy-bind: %v\n", err)
+	}
+
+	for _, name := range []string{"libcapi.so", "libcapi.h"} {
+		_, err = os.Stat(filepath.Join(workdir, name))
+		if err != nil {
+			t.Fatalf("%s not written: %v", name, err)
+		}
+	}
+
+	err = ioutil.WriteFile(filepath.Join(workdir, "main.c"), []byte(`#include <stdio.h>
+#include "libcapi.h"
+
+int main(void) {
+	char *err = NULL;
+	char *s = NULL;
+	capi_handle c;
+	capi_handle d;
+
+	printf("capi_Max: %d\n", capi_Max);
+	printf("capi_Add(1, 2): %lld\n", (long long)capi_Add(1, 2));
+
+	s = capi_Hello("gopy");
+	printf("capi_Hello: %s\n", s);
+	capi_free_string(s);
+	capi_set_Greeting("bonjour");
+	s = capi_get_Greeting();
+	printf("capi_get_Greeting: %s\n", s);
+	capi_free_string(s);
+
+	printf("capi_Div(1, 2): %g\n", capi_Div(1, 2, &err));
+	printf("err: %s\n", err ? err : "<nil>");
+	capi_Div(1, 0, &err);
+	printf("capi_Div(1, 0): err: %s\n", err);
+	capi_free_string(err);
+	err = NULL;
+
+	c = capi_NewCounter("c");
+	capi_Counter_Incr(c, 4, &err);
+	capi_Counter_Incr(c, 7, &err);
+	printf("capi_Counter_Incr: err: %s\n", err);
+	capi_free_string(err);
+	s = capi_Counter_get_Name(c);
+	printf("c: %s=%lld\n", s, (long long)capi_Counter_get_N(c));
+	capi_free_string(s);
+
+	d = capi_Counter_new();
+	capi_Counter_set_N(d, 2);
+	printf("capi_Sum(c, d): %lld\n", (long long)capi_Sum(c, d));
+	capi_free(c);
+	capi_free(d);
+	return 0;
+}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd = exec.Command("cc", "-o", "main", "main.c", "-L.", "-lcapi")
+	cmd.Dir = workdir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error compiling C program: %v\n", err)
+	}
+
+	buf := new(bytes.Buffer)
+	cmd = exec.Command("./main")
+	cmd.Dir = workdir
+	cmd.Env = append(os.Environ(), "LD_LIBRARY_PATH="+workdir)
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running C program: %v\n%s\n", err, buf.String())
+	}
+
+	want := `capi_Max: 10
+capi_Add(1, 2): 3
+capi_Hello: hello gopy
+capi_get_Greeting: bonjour
+capi_Div(1, 2): 0.5
+err: <nil>
+capi_Div(1, 0): err: division by zero
+capi_Counter_Incr: err: c: overflow
+c: c=4
+capi_Sum(c, d): 6
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s\n", got, want)
+	}
+}
+
+// capiWant is the output of the test of the _examples/capi package, which
+// does not depend on the backend.
+const capiWant = `capi.Max = 10
+capi.Add(1, 2) = 3
+capi.Hello('gopy') = hello gopy
+capi.Greeting = 'bonjour'
+capi.GetGreeting() = bonjour
+capi.Hello('gopy') = bonjour gopy
+capi.Div(1, 2) = 0.5
+caught error: division by zero
+c = capi.Counter{Name:"c", N:0}
+caught error: c: overflow
+c.Name = c, c.N = 4
+d = capi.Counter{Name:"d", N:2}
+d.to_dict() = [('N', 2), ('Name', 'd')]
+capi.Sum(c, d) = 6
+caught error: Counter.__init__ takes at most 2 argument(s)
+len(cs) = 2
+cs[0] = capi.Counter{Name:"c0", N:2}
+cs[1] = capi.Counter{Name:"c1", N:2}
+s = s: [1 2] in [1 2]
+s = s: [1 2 5] in [1 5]
+s.Last = capi.Counter{Name:"l", N:5}
+len(s.Values) = 3, s.Values[2] = 5
+sorted(d) = ['Last', 'Name', 'Range', 'Values']
+d['Values'] = [1, 2, 5], d['Range'] = [1, 5]
+d['Last'] = [('N', 5), ('Name', 'l')]
+caught error: Stats.from_dict: unknown field 'Count'
+capi.Record(3) = 1
+capi.Record(1) = 2
+capi.Total = all: [3 1] in [1 3]
+capi.Total.Last.N = 1
+`
+
+func TestBindCAPIPython(t *testing.T) {
+	t.Parallel()
+	// the Counter pointers of Sum are passed as handles by the default
+	// backend too.
+	testPkg(t, pkg{
+		path: "_examples/capi",
+		want: []byte(capiWant),
+	})
+}
+
+func TestBindCFFI(t *testing.T) {
+	t.Parallel()
+	// the cffi module does not depend on the version of python: any
+	// interpreter with cffi runs the test.
+	python := ""
+	for _, exe := range []string{"python2", "python3", "python", "pypy"} {
+		if exec.Command(exe, "-c", "import cffi").Run() == nil {
+			python = exe
+			break
+		}
+	}
+	if python == "" {
+		t.Skip("cffi is not installed")
+	}
+
+	testPkg(t, pkg{
+		path:   "_examples/capi",
+		flags:  []string{"-lang=cffi"},
+		python: python,
+		want:   []byte(capiWant),
+	})
+}
+
+func TestBindNaming(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path:  "_examples/naming",
+		flags: []string{"-naming=pep8"},
+		want: []byte(`naming.MAX_CONNS = 16
+naming.TIMEOUT = 500
+naming.get_timeout() = 500
+naming.default_host = 'localhost'
+naming.get_default_host() = 'example.com'
+naming.none = 'nothing'
+s.host_name = 'example.com'
+s.maxconn = 16
+s.maxconn = 4
+s.address() = 'http://example.com'
+s.to_dict() = [('host_name', 'example.com'), ('maxconn', 4)]
+s.address() = 'http://golang.org'
+naming.print_('hello') = '> hello'
+naming.length('hello') = 5
+naming.to_upper('hello') = 'HELLO'
+naming.Level(2).is_verbose() = True
+naming.http_get('/') = 'GET /'
+hasattr(naming, 'Print') = False
+hasattr(naming, 'Len') = False
+hasattr(naming, 'NewHTTPServer') = False
+hasattr(naming, 'MaxConns') = False
+hasattr(naming, 'GetDefaultHost') = False
+hasattr(naming, 'HttpGet') = False
+`),
+	})
+}
+
+func TestBindConfig(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path: "_examples/config",
+		want: []byte(`config.VERSION_STRING = '1.0'
+config.RED = <Color.RED: 1>
+config.Color(1) = <Color.RED: 1>
+isinstance(config.RED, int) = True
+isinstance(config.READ, int) = False
+type(config.WRITE) = Mode
+p.x, p.y = 3, 4
+p.name = 'p'
+p.norm2() = 25
+p.to_dict() = [('name', 'p'), ('x', 3), ('y', 4)]
+config.origin().name = 'origin'
+config.sum_(100) = 5050
+config.twice(21) = 42
+hasattr(config, 'debug') = False
+hasattr(config, 'get_debug') = False
+hasattr(config, 'Internal') = False
+hasattr(config, 'describe') = False
+hasattr(config, 'double') = False
+hasattr(p, 'secret') = False
+hasattr(p, 'reset') = False
+hasattr(p, 'label') = False
+simple.Answer(1, 'a') = 42
+`),
+	})
+}
+
+func TestBindDirectives(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path: "_examples/directives",
+		want: []byte(`directives.LIMIT = 100
+r.W, r.H = 2, 3
+r.label = 'rect'
+r.Area = 6
+r.Area = 24
+caught: r.Area is read-only
+directives.Rect.Area.__doc__ = 'Area() int\n\nArea is the area of the rectangle.\n'
+r.to_dict() = [('H', 6), ('W', 4), ('label', 'rect')]
+directives.Square(4).Area = 16
+directives.Count(100, 7) = 14
+directives.Unknown() = 42
+hasattr(directives, 'Limit') = False
+hasattr(directives, 'Verbose') = False
+hasattr(directives, 'GetVerbose') = False
+hasattr(directives, 'Hidden') = False
+hasattr(directives, 'Reveal') = False
+hasattr(r, 'Tag') = False
+hasattr(r, 'Cache') = False
+hasattr(r, 'Debug') = False
+hasattr(r, 'Scale') = False
+`),
+	})
+}
+
+func TestBindShim(t *testing.T) {
+	t.Parallel()
+	testPkg(t, pkg{
+		path:  "_examples/shim",
+		flags: []string{"-lang=shim"},
+		want: []byte(`shim.__doc__ = 'Package shim tests the python layer over the extension module of a Go\npackage.'
+shim.MAX_SPEED = 120
+shim.default_owner = 'nobody'
+shim.get_default_owner() = 'alice'
+type(v) = Vehicle
+v.owner = 'alice'
+v.http_endpoint = 'http://alice'
+shim.Vehicle.accelerate.__doc__ = 'Accelerate increases the speed of the vehicle by dv.'
+v.speed = 100
+caught GoError: too fast (RuntimeError: True)
+caught AttributeError: v.speed is read-only
+w.owner = 'bob', w.speed = 0
+shim.total_speed(v, w) = 100
+type(c.lead) = Vehicle
+c.lead.owner = 'alice'
+c.follower.owner = 'bob'
+shim.parse_speed('42') = 42
+caught GoError: strconv.Atoi: parsing "fast": invalid syntax
+type(d) = Vehicle
+sorted(d.to_dict().items()) = [('http_endpoint', 'http://carol'), ('owner', 'carol'), ('speed', 0)]
+`),
+	})
+}
+
+func TestBindCython(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("cython"); err != nil {
+		t.Skip("cython is not installed")
+	}
+
+	workdir, err := ioutil.TempDir("", "gopy-")
+	if err != nil {
+		t.Fatalf("could not create workdir: %v\n", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	for _, args := range [][]string{
+		{"bind", "-output=" + workdir},
+		{"gen", "-lang=go", "-output=" + workdir},
+		{"gen", "-lang=cython", "-output=" + workdir},
+	} {
+		cmd := exec.Command("gopy", append(args, "./_examples/hi")...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if err != nil {
+			t.Fatalf("error running gopy %s: %v\n", args[0], err)
+		}
+	}
+
+	err = ioutil.WriteFile(filepath.Join(workdir, "fast.pyx"), []byte(`
+from hi cimport GoInt, GoString, cgo_func_hi_Add, cgo_func_hi_Person_Greet, cpy_type_hi_Person
+
+import hi
+
+def add(GoInt i, GoInt j):
+    return cgo_func_hi_Add(i, j)
+
+def greet(p):
+    if not isinstance(p, hi.Person):
+        raise TypeError("expected a hi.Person")
+    cdef GoString s = cgo_func_hi_Person_Greet((<cpy_type_hi_Person*>p).cgopy)
+    return s.p[:s.n]
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(workdir, "test.py"), []byte(`import fast
+import hi
+
+print("fast.add(1, 2) = %d" % fast.add(1, 2))
+print("fast.greet(p) = %s" % fast.greet(hi.NewPerson("foo", 42)))
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inc, err := exec.Command(
+		"python2", "-c",
+		"import distutils.sysconfig; print(distutils.sysconfig.get_python_inc())",
+	).Output()
+	if err != nil {
+		t.Fatalf("could not find the python include directory: %v\n", err)
+	}
+
+	for _, args := range [][]string{
+		{"cython", "-2", "-o", "fast.c", "fast.pyx"},
+		{"cc", "-shared", "-fPIC", "-o", "fast.so", "fast.c",
+			"-I.", "-I" + strings.TrimSpace(string(inc)), "-L.", "-l:hi.so",
+		},
+	} {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = workdir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if err != nil {
+			t.Fatalf("error running %s: %v\n", args[0], err)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	cmd := exec.Command("python2", "./test.py")
+	cmd.Dir = workdir
+	cmd.Env = append(os.Environ(), "LD_LIBRARY_PATH="+workdir)
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("error running python module: %v\n%s\n", err, buf.String())
+	}
+
+	want := `fast.add(1, 2) = 3
+fast.greet(p) = Hello, I am foo
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s\n", got, want)
+	}
+}
+
+func TestGenList(t *testing.T) {
+	t.Parallel()
+	stdout := new(bytes.Buffer)
+	cmd := exec.Command("gopy", "gen", "-lang=list")
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("error running gopy gen -lang=list: %v\n", err)
+	}
+
+	want := `c       cgo package exposing the Go package through a C API
+cffi    pure-python module calling the C API with cffi
+cython  Cython declarations of the cgo functions
+go      cgo package exporting the Go package to C
+py2     CPython-2 C extension module
+py2low  CPython-2 C extension module _<pkg> for the shim
+shim    pure-python layer over the py2low extension module
+`
+	if got := stdout.String(); got != want {
+		t.Fatalf("gopy gen -lang=list:\ngot:\n%s\nwant:\n%s\n", got, want)
+	}
+}